@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wowsims/sod/sim/core"
+	"github.com/wowsims/sod/sim/core/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var aplCmd = &cobra.Command{
+	Use:   "apl",
+	Short: "convert and check APL rotations",
+	Long:  "convert APL rotations between their json format and a text format, which is easier to read and diff, and check them for problems",
+}
+
+var aplFormatCmd = &cobra.Command{
+	Use:   "format [rotation.apl.json]",
+	Short: "print the text format of an APL rotation",
+	Long:  "print the text format of an APL rotation stored as protojson",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		silenceRunErrors(cmd)
+		rotation := &proto.APLRotation{}
+		loadProtoJson(args[0], rotation)
+		writeOutput(outfile, []byte(core.FormatAPLRotation(rotation)))
+		return nil
+	},
+}
+
+var aplParseCmd = &cobra.Command{
+	Use:   "parse [rotation.apl]",
+	Short: "parse the text format of an APL rotation",
+	Long:  "parse the text format of an APL rotation, and print it as protojson",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		silenceRunErrors(cmd)
+		text, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read %q: %w", args[0], err)
+		}
+		rotation, err := core.ParseAPLRotation(string(text))
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %w", args[0], err)
+		}
+		writeOutput(outfile, []byte(protojson.Format(rotation)))
+		return nil
+	},
+}
+
+var aplLintCmd = &cobra.Command{
+	Use:   "lint [rotation.apl]",
+	Short: "check an APL rotation for problems",
+	Long:  "print the validation warnings of every entry of a player's APL rotation, along with static problems such as unreachable entries, and fail if there are any",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  aplLintMain,
+}
+
+var (
+	aplLintInfile string
+	aplLintFormat string
+)
+
+func init() {
+	aplLintCmd.Flags().StringVar(&aplLintInfile, "infile", "", "location of input file (LintAPLRotationRequest in protojson format)")
+	aplLintCmd.Flags().StringVar(&link, "link", "", "wowsims share link of an individual sim setup, instead of --infile")
+	aplLintCmd.Flags().StringVar(&aplLintFormat, "format", "text", "output format of the warnings, text or json")
+	aplLintCmd.MarkFlagsMutuallyExclusive("infile", "link")
+	aplLintCmd.MarkFlagsOneRequired("infile", "link")
+
+	for _, cmd := range []*cobra.Command{aplFormatCmd, aplParseCmd, aplLintCmd} {
+		cmd.Flags().StringVar(&outfile, "outfile", "", "location of output file, defaults to stdout")
+		aplCmd.AddCommand(cmd)
+	}
+}
+
+// Lints the rotation of the player in --infile or --link, or the rotation in the given file instead,
+// which is parsed as protojson if it has a .json extension and as the text format otherwise.
+func aplLintMain(cmd *cobra.Command, args []string) error {
+	silenceRunErrors(cmd)
+	input := &proto.LintAPLRotationRequest{}
+	if link != "" {
+		settings, err := decodeSettingsLink(link)
+		if err != nil {
+			return fmt.Errorf("failed to load link: %w", err)
+		}
+		individualSettings, ok := settings.(*proto.IndividualSimSettings)
+		if !ok {
+			return fmt.Errorf("unsupported settings type %T, expected an individual sim link", settings)
+		}
+		input.Player = individualSettings.Player
+		input.RaidBuffs = individualSettings.RaidBuffs
+		input.PartyBuffs = individualSettings.PartyBuffs
+		input.Debuffs = individualSettings.Debuffs
+		input.Encounter = individualSettings.Encounter
+	} else {
+		loadProtoJson(aplLintInfile, input)
+	}
+
+	if len(args) > 0 {
+		if strings.HasSuffix(args[0], ".json") {
+			input.Rotation = &proto.APLRotation{}
+			loadProtoJson(args[0], input.Rotation)
+		} else {
+			text, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read %q: %w", args[0], err)
+			}
+			input.Rotation, err = core.ParseAPLRotation(string(text))
+			if err != nil {
+				return fmt.Errorf("failed to parse %q: %w", args[0], err)
+			}
+		}
+	}
+
+	result := core.LintAPLRotation(input)
+	if result.Error != nil {
+		return fmt.Errorf("lint failed: %s", result.Error.Message)
+	}
+
+	switch aplLintFormat {
+	case "text":
+		var sb strings.Builder
+		for _, warning := range result.Warnings {
+			fmt.Fprintf(&sb, "%s: %s\n", aplLintLocation(warning), warning.Message)
+		}
+		writeOutput(outfile, []byte(sb.String()))
+	case "json":
+		writeOutput(outfile, marshalProtoJson(result))
+	default:
+		return fmt.Errorf("unknown output format %q, expected text or json", aplLintFormat)
+	}
+
+	if len(result.Warnings) > 0 {
+		return fmt.Errorf("found %d warnings", len(result.Warnings))
+	}
+	return nil
+}
+
+// Once the arguments are valid, errors are about the input rather than the usage of the command, so
+// only print them once from Execute instead of along with the usage.
+func silenceRunErrors(cmd *cobra.Command) {
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+}
+
+// Returns the location of the entry of a warning, using the section names of the text format and
+// 1-based entry numbers.
+func aplLintLocation(warning *proto.APLLintWarning) string {
+	switch {
+	case warning.Prepull:
+		return fmt.Sprintf("prepull #%d", warning.Index+1)
+	case warning.ListName != "":
+		return fmt.Sprintf("list %q #%d", warning.ListName, warning.Index+1)
+	default:
+		return fmt.Sprintf("priority #%d", warning.Index+1)
+	}
+}
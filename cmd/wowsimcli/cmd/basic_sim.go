@@ -3,12 +3,10 @@ package cmd
 import (
 	"fmt"
 	"log"
-	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/wowsims/sod/sim/core"
 	"github.com/wowsims/sod/sim/core/proto"
-	"google.golang.org/protobuf/encoding/protojson"
 )
 
 var simCmd = &cobra.Command{
@@ -17,26 +15,44 @@ var simCmd = &cobra.Command{
 	Run:   simMain,
 }
 
+var (
+	link         string
+	topEntries   int
+	replaySeed   int64
+	replayDps    float64
+	replayPlayer int32
+)
+
 func init() {
 	simCmd.Flags().StringVar(&infile, "infile", "input.json", "location of input file (RaidSimRequest in protojson format)")
+	simCmd.Flags().StringVar(&link, "link", "", "wowsims share link to build the RaidSimRequest from, instead of --infile")
 	simCmd.Flags().StringVar(&outfile, "outfile", "", "location of output file, defaults to stdout")
+	simCmd.Flags().StringVar(&format, "format", "json", "output format of the results, table, json or csv")
+	simCmd.Flags().IntVar(&topEntries, "top", 5, "number of actions and auras to list per player in table and csv output")
+	simCmd.Flags().Int64Var(&replaySeed, "replay-seed", 0, "only re-run the iteration with this seed (e.g. a min_seed or max_seed) with debug logs on")
+	simCmd.Flags().Float64Var(&replayDps, "replay-dps", 0, "DPS recorded for the replayed iteration, fails if the replay does not match it")
+	simCmd.Flags().Int32Var(&replayPlayer, "replay-player", -1, "raid index of the player whose DPS is compared to --replay-dps, defaults to the raid DPS")
 	simCmd.Flags().BoolVar(&verbose, "verbose", false, "print information during runtime")
-	simCmd.MarkFlagRequired("infile")
+	simCmd.MarkFlagsMutuallyExclusive("infile", "link")
 }
 
 func simMain(cmd *cobra.Command, args []string) {
-	data, err := os.ReadFile(infile)
-	if err != nil {
-		log.Fatalf("failed to load input json file %q: %v", infile, err)
-	}
 	input := &proto.RaidSimRequest{}
+	if link != "" {
+		var err error
+		input, err = raidSimRequestFromLink(link)
+		if err != nil {
+			log.Fatalf("failed to load link: %s", err)
+		}
+	} else {
+		loadProtoJson(infile, input)
+	}
 
-	err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, input)
-	if err != nil {
-		log.Fatalf("failed to load input json file: %s", err)
+	if replaySeed != 0 {
+		replayMain(input)
+		return
 	}
 
-	var output []byte
 	reporter := make(chan *proto.ProgressMetrics, 10)
 	core.RunRaidSimConcurrentAsync(input, reporter, "cmd-raid-sim")
 
@@ -51,20 +67,52 @@ func simMain(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	output, err = protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(finalResult)
-	if err != nil {
-		log.Fatalf("failed to marshal final results: %s", err)
+	if finalResult.Error != nil {
+		log.Fatalf("sim failed: %s", finalResult.Error.Message)
 	}
 
-	if outfile == "" {
-		fmt.Print(string(output))
-	} else {
-		err = os.WriteFile(outfile, output, 0666)
-		if err != nil {
-			log.Fatalf("failed to write output file:: %s", err)
-		}
-		if verbose {
-			fmt.Printf("Wrote output file: `%s` successfully.\n", outfile)
-		}
+	switch format {
+	case "table":
+		writeOutput(outfile, []byte(printSummaryTable(summarizeRaidSim(finalResult, input.SimOptions.GetIterations(), topEntries))))
+	case "csv":
+		writeOutput(outfile, []byte(printSummaryCsv(summarizeRaidSim(finalResult, input.SimOptions.GetIterations(), topEntries))))
+	case "json":
+		writeOutput(outfile, marshalProtoJson(finalResult))
+	default:
+		log.Fatalf("unknown output format %q, expected table, json or csv", format)
+	}
+}
+
+func replayMain(input *proto.RaidSimRequest) {
+	request := &proto.ReplayIterationRequest{
+		Request:     input,
+		Seed:        replaySeed,
+		ExpectedDps: replayDps,
+	}
+	if replayPlayer >= 0 {
+		request.Player = &proto.UnitReference{Type: proto.UnitReference_Player, Index: replayPlayer}
+	}
+
+	replay := core.ReplayIteration(request)
+	if replay.Error != nil {
+		log.Fatalf("replay failed: %s", replay.Error.Message)
+	}
+
+	switch format {
+	case "table":
+		writeOutput(outfile, []byte(replay.Result.Logs+"\n"+printSummaryTable(summarizeRaidSim(replay.Result, 1, topEntries))))
+	case "csv":
+		writeOutput(outfile, []byte(printSummaryCsv(summarizeRaidSim(replay.Result, 1, topEntries))))
+	case "json":
+		writeOutput(outfile, marshalProtoJson(replay))
+	default:
+		log.Fatalf("unknown output format %q, expected table, json or csv", format)
+	}
+
+	if replayDps != 0 && !replay.DpsMatches {
+		log.Fatalf("replayed iteration had %0.3f DPS, expected %0.3f", replay.Dps, replayDps)
+	}
+	if verbose {
+		fmt.Printf("Replayed seed %d: %0.3f DPS\n", replaySeed, replay.Dps)
 	}
 }
@@ -11,13 +11,13 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/wowsims/sod/sim/core"
 	"github.com/wowsims/sod/sim/core/proto"
-	"google.golang.org/protobuf/encoding/protojson"
 )
 
 var (
 	infile      string
 	replacefile string
 	outfile     string
+	format      string
 	verbose     bool
 )
 
@@ -29,38 +29,40 @@ var bulkCmd = &cobra.Command{
 }
 
 func init() {
-	bulkCmd.Flags().StringVar(&infile, "infile", "input.json", "location of input file (RaidSimRequest in protojson format)")
+	bulkCmd.Flags().StringVar(&infile, "infile", "input.json", "location of input file (BulkSimRequest in protojson format, or RaidSimRequest when --replacefile is set)")
 	bulkCmd.Flags().StringVar(&replacefile, "replacefile", "", "location of replacement items file. Writes a CSV result of the items replaced instead of JSON")
+	bulkCmd.Flags().StringVar(&format, "format", "json", "output format of the results, json or csv")
 	bulkCmd.Flags().StringVar(&outfile, "output", "", "location of output file, defaults to stdout")
 	bulkCmd.Flags().BoolVar(&verbose, "verbose", false, "print information during runtime")
 	bulkCmd.MarkFlagRequired("infile")
-	bulkCmd.MarkFlagRequired("replacefile")
 }
 
 func bulkSimMain(cmd *cobra.Command, args []string) {
-	data, err := os.ReadFile(infile)
-	if err != nil {
-		log.Fatalf("failed to load input json file %q: %v", infile, err)
+	if replacefile != "" {
+		input := &proto.RaidSimRequest{}
+		loadProtoJson(infile, input)
+		writeOutput(outfile, []byte(BulkSim(input, replacefile, verbose)))
+		return
 	}
-	input := &proto.RaidSimRequest{}
 
-	err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, input)
-	if err != nil {
-		log.Fatalf("failed to load input json file: %s", err)
-	}
+	request := &proto.BulkSimRequest{}
+	loadProtoJson(infile, request)
 
-	output := BulkSim(input, replacefile, verbose)
+	result := runBulkSim(request, verbose)
+	if result == nil {
+		log.Fatalf("bulk sim finished without a result")
+	}
+	if result.Error != nil {
+		log.Fatalf("bulk sim failed: %s", result.Error.Message)
+	}
 
-	if outfile == "" {
-		print(string(output))
-	} else {
-		err = os.WriteFile(outfile, []byte(output), 0666)
-		if err != nil {
-			log.Fatalf("failed to write output file:: %s", err)
-		}
-		if verbose {
-			fmt.Printf("Wrote output file: `%s` successfully.\n", outfile)
-		}
+	switch format {
+	case "csv":
+		writeOutput(outfile, []byte(printCombos(result)))
+	case "json":
+		writeOutput(outfile, marshalProtoJson(result))
+	default:
+		log.Fatalf("unknown output format %q, expected json or csv", format)
 	}
 }
 
@@ -96,59 +98,42 @@ func BulkSim(input *proto.RaidSimRequest, replaceFile string, verbose bool) stri
 			FastMode:           replaceInput.FastMode,
 		},
 	}
+	result := runBulkSim(bsr, verbose)
+	if result == nil {
+		return ""
+	}
+	if result.Error != nil {
+		fmt.Printf("Failed: %s\n", result.Error.Message)
+		return ""
+	}
+	return printCombos(result)
+}
+
+// runBulkSim runs bsr to completion, printing progress along the way if verbose
+// is set, and returns the final result.
+func runBulkSim(bsr *proto.BulkSimRequest, verbose bool) *proto.BulkSimResult {
 	progress := make(chan *proto.ProgressMetrics, 100)
 	core.RunBulkSimAsync(bsr, progress, "cmd-bulk-sim")
 
 	startTime := time.Now()
 
-	c := time.After(time.Minute)
 	var lastTotal int32
-	for {
-		select {
-		case status, ok := <-progress:
-			if !ok {
-				return ""
-			}
-			if status.FinalBulkResult != nil {
-				if status.FinalBulkResult.Error != nil {
-					fmt.Printf("Failed: %s\n", status.FinalBulkResult.Error.Message)
-				} else {
-					return printCombos(status.FinalBulkResult)
-				}
-			}
+	for status := range progress {
+		if status.FinalBulkResult != nil {
+			return status.FinalBulkResult
+		}
 
-			if verbose {
-				if lastTotal != status.TotalSims {
-					if lastTotal > status.TotalSims {
-						fmt.Printf("Refining results, running the best combos with more iterations...\n")
-					}
-					lastTotal = status.TotalSims
-				}
-				compl := status.CompletedIterations
-				if compl == 0 {
-					break
-				}
-				elapsed := time.Since(startTime)
-				perDone := float64(compl) / float64(status.TotalIterations)
-				totalTime := time.Duration(float64(elapsed) / perDone)
-				var timeEst string
-				if totalTime.Hours() > 48 {
-					// use days
-					timeEst = fmt.Sprintf("Estimated Time: %0.1f / %0.1f days", elapsed.Hours()/24, totalTime.Hours()/24)
-				} else if totalTime.Minutes() > 120 {
-					// use hours
-					timeEst = fmt.Sprintf("Estimated Time: %0.1f / %0.1f hours", elapsed.Hours(), totalTime.Hours())
-				} else {
-					timeEst = fmt.Sprintf("Estimated Time: %0.1f / %0.1f minutes", elapsed.Minutes(), totalTime.Minutes())
+		if verbose {
+			if lastTotal != status.TotalSims {
+				if lastTotal > status.TotalSims {
+					fmt.Printf("Refining results, running the best combos with more iterations...\n")
 				}
-				totalStr := strconv.Itoa(int(status.TotalIterations))
-				fmtStr := "%" + strconv.Itoa(len(totalStr)) + ".f"
-				fmt.Printf("Sim Progress: "+fmtStr+" / %d | %s  (completed %d / %d)\n", float64(compl), status.TotalIterations, timeEst, status.CompletedSims, status.TotalSims)
+				lastTotal = status.TotalSims
 			}
-		case <-c:
-
+			printProgress(status, startTime)
 		}
 	}
+	return nil
 }
 
 func printCombos(results *proto.BulkSimResult) string {
@@ -180,3 +165,28 @@ func printCombo(combo *proto.BulkComboResult) string {
 	itemtext += "]"
 	return fmt.Sprintf("%s,%0.1f\n", itemtext, combo.UnitMetrics.Dps.Avg)
 }
+
+// printProgress prints the completed iterations of status along with an estimate
+// of the total run time based on how long the sim has taken since startTime.
+func printProgress(status *proto.ProgressMetrics, startTime time.Time) {
+	compl := status.CompletedIterations
+	if compl == 0 || status.TotalIterations == 0 {
+		return
+	}
+	elapsed := time.Since(startTime)
+	perDone := float64(compl) / float64(status.TotalIterations)
+	totalTime := time.Duration(float64(elapsed) / perDone)
+	var timeEst string
+	if totalTime.Hours() > 48 {
+		// use days
+		timeEst = fmt.Sprintf("Estimated Time: %0.1f / %0.1f days", elapsed.Hours()/24, totalTime.Hours()/24)
+	} else if totalTime.Minutes() > 120 {
+		// use hours
+		timeEst = fmt.Sprintf("Estimated Time: %0.1f / %0.1f hours", elapsed.Hours(), totalTime.Hours())
+	} else {
+		timeEst = fmt.Sprintf("Estimated Time: %0.1f / %0.1f minutes", elapsed.Minutes(), totalTime.Minutes())
+	}
+	totalStr := strconv.Itoa(int(status.TotalIterations))
+	fmtStr := "%" + strconv.Itoa(len(totalStr)) + ".f"
+	fmt.Printf("Sim Progress: "+fmtStr+" / %d | %s  (completed %d / %d)\n", float64(compl), status.TotalIterations, timeEst, status.CompletedSims, status.TotalSims)
+}
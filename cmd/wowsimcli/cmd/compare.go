@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wowsims/sod/sim/core"
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "compare setups with paired iterations",
+	Long:  "compare the DPS of one or more variants to a base setup, running every setup with the same per-iteration seeds",
+	Run:   compareMain,
+}
+
+var (
+	variantLinks  []string
+	comparePlayer int32
+	compareFormat string
+)
+
+func init() {
+	compareCmd.Flags().StringVar(&infile, "infile", "input.json", "location of input file (CompareSimsRequest in protojson format)")
+	compareCmd.Flags().StringVar(&link, "link", "", "wowsims share link of the base setup, instead of --infile")
+	compareCmd.Flags().StringArrayVar(&variantLinks, "variant-link", nil, "wowsims share link of a setup to compare to --link, can be repeated")
+	compareCmd.Flags().Int32Var(&comparePlayer, "player", -1, "raid index of the player whose DPS is compared, defaults to the raid DPS")
+	compareCmd.Flags().StringVar(&outfile, "outfile", "", "location of output file, defaults to stdout")
+	compareCmd.Flags().StringVar(&compareFormat, "format", "table", "output format of the results, table, json or csv")
+	compareCmd.Flags().BoolVar(&verbose, "verbose", false, "print information during runtime")
+	compareCmd.MarkFlagsMutuallyExclusive("infile", "link")
+	compareCmd.MarkFlagsRequiredTogether("link", "variant-link")
+}
+
+func compareMain(cmd *cobra.Command, args []string) {
+	input := &proto.CompareSimsRequest{}
+	if link != "" {
+		base, err := raidSimRequestFromLink(link)
+		if err != nil {
+			log.Fatalf("failed to load link: %s", err)
+		}
+		input.Base = base
+		for _, variantLink := range variantLinks {
+			variant, err := raidSimRequestFromLink(variantLink)
+			if err != nil {
+				log.Fatalf("failed to load variant link: %s", err)
+			}
+			input.Variants = append(input.Variants, variant)
+		}
+	} else {
+		loadProtoJson(infile, input)
+	}
+	if comparePlayer >= 0 {
+		input.Player = &proto.UnitReference{Type: proto.UnitReference_Player, Index: comparePlayer}
+	}
+
+	if verbose {
+		fmt.Printf("Comparing %d variants over %d iterations\n", len(input.Variants), input.Base.GetSimOptions().GetIterations())
+	}
+
+	result := core.CompareSims(input)
+	if result.Error != nil {
+		log.Fatalf("compare failed: %s", result.Error.Message)
+	}
+
+	switch compareFormat {
+	case "table":
+		writeOutput(outfile, []byte(printComparisonTable(result)))
+	case "csv":
+		writeOutput(outfile, []byte(printComparisonCsv(result)))
+	case "json":
+		writeOutput(outfile, marshalProtoJson(result))
+	default:
+		log.Fatalf("unknown output format %q, expected table, json or csv", compareFormat)
+	}
+}
+
+func printComparisonTable(result *proto.CompareSimsResult) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Base: %0.1f DPS over %d iterations\n\n", result.BaseDps, result.Iterations)
+	fmt.Fprintln(w, "Variant\tDPS\tDiff\tStderr\tp\tSignificant")
+	for i, variant := range result.Variants {
+		fmt.Fprintf(w, "%d\t%0.1f\t%+0.2f\t%0.2f\t%0.4f\t%t\n", i+1, variant.Dps, variant.DpsDiff, variant.DpsDiffStderr, variant.PValue, variant.Significant)
+	}
+
+	w.Flush()
+	return buf.String()
+}
+
+func printComparisonCsv(result *proto.CompareSimsResult) string {
+	output := "variant,dps,dps_diff,dps_diff_stderr,p_value,significant\n"
+	output += fmt.Sprintf("base,%0.4f,0,0,1,false\n", result.BaseDps)
+	for i, variant := range result.Variants {
+		output += fmt.Sprintf("%d,%0.4f,%0.4f,%0.4f,%0.6f,%t\n", i+1, variant.Dps, variant.DpsDiff, variant.DpsDiffStderr, variant.PValue, variant.Significant)
+	}
+	return output
+}
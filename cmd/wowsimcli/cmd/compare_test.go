@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wowsims/sod/sim"
+	"github.com/wowsims/sod/sim/core/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func init() {
+	sim.RegisterAll()
+}
+
+func TestCompareDefaultsToTable(t *testing.T) {
+	player := &proto.Player{
+		Name:      "Mage",
+		Class:     proto.Class_ClassMage,
+		Level:     60,
+		Spec:      &proto.Player_Mage{Mage: &proto.Mage{Options: &proto.Mage_Options{}}},
+		Equipment: &proto.EquipmentSpec{},
+		Rotation:  &proto.APLRotation{Type: proto.APLRotation_TypeAPL},
+	}
+	request := &proto.RaidSimRequest{
+		Raid: &proto.Raid{Parties: []*proto.Party{{Players: []*proto.Player{player}}}},
+		Encounter: &proto.Encounter{
+			Duration: 30,
+			Targets:  []*proto.Target{{Name: "target", Level: 63}},
+		},
+		SimOptions: &proto.SimOptions{Iterations: 5, RandomSeed: 1},
+	}
+	input, err := protojson.Marshal(&proto.CompareSimsRequest{Base: request, Variants: []*proto.RaidSimRequest{request}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Run from a directory containing input.json, so no flags are needed.
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "input.json"), input, 0666); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	compareMain(compareCmd, nil)
+	os.Stdout = stdout
+	writer.Close()
+
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(output), "Base: ") || !strings.Contains(string(output), "Variant") {
+		t.Errorf("Expected a table by default, got:\n%s", output)
+	}
+}
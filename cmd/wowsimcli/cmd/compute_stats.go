@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/wowsims/sod/sim/core"
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "compute character stats",
+	Long:  "compute character stats taking into account gear, buffs, consumes, etc.",
+	Run:   statsMain,
+}
+
+func init() {
+	statsCmd.Flags().StringVar(&infile, "infile", "input.json", "location of input file (ComputeStatsRequest in protojson format)")
+	statsCmd.Flags().StringVar(&outfile, "outfile", "", "location of output file, defaults to stdout")
+	statsCmd.Flags().BoolVar(&verbose, "verbose", false, "print information during runtime")
+	statsCmd.MarkFlagRequired("infile")
+}
+
+func statsMain(cmd *cobra.Command, args []string) {
+	input := &proto.ComputeStatsRequest{}
+	loadProtoJson(infile, input)
+
+	result := core.ComputeStats(input)
+	if result.ErrorResult != "" {
+		log.Fatalf("failed to compute stats: %s", result.ErrorResult)
+	}
+
+	writeOutput(outfile, marshalProtoJson(result))
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wowsims/sod/sim/core/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	goproto "google.golang.org/protobuf/proto"
+)
+
+var baseURL string
+
+var encodeLinkCmd = &cobra.Command{
+	Use:   "encodelink [file]",
+	Short: "encode settings into a wowsims link/url",
+	Long:  "encode a RaidSimRequest, IndividualSimSettings or RaidSimSettings protojson file into a wowsims link/url",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return encodeLink(args[0])
+	},
+}
+
+func init() {
+	encodeLinkCmd.Flags().StringVar(&baseURL, "baseurl", "https://wowsims.github.io/sod/", "url the sims are served from")
+}
+
+func encodeLink(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("cannot read input file %q: %w", file, err)
+	}
+
+	settings, err := parseLinkSettings(data)
+	if err != nil {
+		return err
+	}
+
+	link, err := encodeSettingsLink(baseURL, settings)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(link)
+	return nil
+}
+
+// parseLinkSettings tries each supported message in turn, so the input type
+// doesn't need to be specified. Parsing is strict, as unknown fields are what
+// tell the message types apart.
+func parseLinkSettings(data []byte) (goproto.Message, error) {
+	individual := &proto.IndividualSimSettings{}
+	if err := protojson.Unmarshal(data, individual); err == nil {
+		return individual, nil
+	}
+
+	raid := &proto.RaidSimSettings{}
+	if err := protojson.Unmarshal(data, raid); err == nil {
+		return raid, nil
+	}
+
+	request := &proto.RaidSimRequest{}
+	if err := protojson.Unmarshal(data, request); err != nil {
+		return nil, fmt.Errorf("input is not a RaidSimRequest, IndividualSimSettings or RaidSimSettings: %w", err)
+	}
+	return settingsFromRaidSimRequest(request), nil
+}
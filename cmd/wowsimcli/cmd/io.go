@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	googleProto "google.golang.org/protobuf/proto"
+)
+
+// loadProtoJson reads the protojson encoded message stored at path into msg.
+func loadProtoJson(path string, msg googleProto.Message) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("failed to load input json file %q: %v", path, err)
+	}
+	err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, msg)
+	if err != nil {
+		log.Fatalf("failed to load input json file: %s", err)
+	}
+}
+
+func marshalProtoJson(msg googleProto.Message) []byte {
+	output, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
+		log.Fatalf("failed to marshal final results: %s", err)
+	}
+	return output
+}
+
+// writeOutput writes output to path, or to stdout if path is empty.
+func writeOutput(path string, output []byte) {
+	if path == "" {
+		fmt.Print(string(output))
+		return
+	}
+	err := os.WriteFile(path, output, 0666)
+	if err != nil {
+		log.Fatalf("failed to write output file:: %s", err)
+	}
+	if verbose {
+		fmt.Printf("Wrote output file: `%s` successfully.\n", path)
+	}
+}
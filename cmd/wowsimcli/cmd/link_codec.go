@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/wowsims/sod/sim/core"
+	"github.com/wowsims/sod/sim/core/proto"
+	goproto "google.golang.org/protobuf/proto"
+)
+
+var errInvalidLink = errors.New("invalid wowsims export link")
+
+// decodeSettingsLink decodes a wowsims share link into either RaidSimSettings
+// or IndividualSimSettings, depending on which sim the link points at.
+func decodeSettingsLink(link string) (goproto.Message, error) {
+	parts := strings.Split(link, "#")
+	switch {
+	case len(parts) != 2:
+		return nil, errInvalidLink
+	case parts[1] == "":
+		return nil, errInvalidLink
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode proto from link: %w", err)
+	}
+
+	r, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create zlib reader: %w", err)
+	}
+	defer r.Close()
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, fmt.Errorf("reading zlib data failed: %w", err)
+	}
+
+	var settings goproto.Message
+	if strings.Contains(link, "/raid/") {
+		settings = &proto.RaidSimSettings{}
+	} else {
+		settings = &proto.IndividualSimSettings{}
+	}
+
+	if err := goproto.Unmarshal(buf.Bytes(), settings); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal raw proto: %w", err)
+	}
+
+	return settings, nil
+}
+
+// encodeSettingsLink encodes settings, either RaidSimSettings or
+// IndividualSimSettings, into a share link for the matching sim under baseURL.
+func encodeSettingsLink(baseURL string, settings goproto.Message) (string, error) {
+	var path string
+	switch settings := settings.(type) {
+	case *proto.RaidSimSettings:
+		path = "raid"
+	case *proto.IndividualSimSettings:
+		if settings.Player.GetSpec() == nil {
+			return "", errors.New("player has no spec set")
+		}
+		path = specPath(core.PlayerProtoToSpec(settings.Player))
+	default:
+		return "", fmt.Errorf("unsupported settings type %T", settings)
+	}
+
+	data, err := goproto.Marshal(settings)
+	if err != nil {
+		return "", fmt.Errorf("cannot marshal settings: %w", err)
+	}
+
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return "", fmt.Errorf("writing zlib data failed: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("writing zlib data failed: %w", err)
+	}
+
+	return strings.TrimSuffix(baseURL, "/") + "/" + path + "/#" + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// specPath returns the directory the UI serves spec from, e.g.
+// SpecBalanceDruid is served from balance_druid.
+func specPath(spec proto.Spec) string {
+	var sb strings.Builder
+	for i, r := range strings.TrimPrefix(spec.String(), "Spec") {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				sb.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -17,7 +17,12 @@ func Execute(version string) {
 	rootCmd.AddCommand(newVersionCommand(version))
 	rootCmd.AddCommand(simCmd)
 	rootCmd.AddCommand(bulkCmd)
+	rootCmd.AddCommand(compareCmd)
+	rootCmd.AddCommand(weightsCmd)
+	rootCmd.AddCommand(statsCmd)
 	rootCmd.AddCommand(decodeLinkCmd)
+	rootCmd.AddCommand(encodeLinkCmd)
+	rootCmd.AddCommand(aplCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
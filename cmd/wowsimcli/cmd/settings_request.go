@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/wowsims/sod/sim/core"
+	"github.com/wowsims/sod/sim/core/proto"
+	goproto "google.golang.org/protobuf/proto"
+)
+
+// Matches the defaults used by the UI when the settings leave them unset.
+const (
+	defaultIterations = 3000
+	maxRngSeed        = 1<<32 - 1
+)
+
+// raidSimRequestFromLink builds the RaidSimRequest the UI would run for the
+// settings stored in a wowsims share link.
+func raidSimRequestFromLink(link string) (*proto.RaidSimRequest, error) {
+	settings, err := decodeSettingsLink(link)
+	if err != nil {
+		return nil, err
+	}
+
+	switch settings := settings.(type) {
+	case *proto.IndividualSimSettings:
+		return individualSimRequest(settings), nil
+	case *proto.RaidSimSettings:
+		return raidSimRequest(settings), nil
+	default:
+		return nil, fmt.Errorf("unsupported settings type %T", settings)
+	}
+}
+
+func individualSimRequest(settings *proto.IndividualSimSettings) *proto.RaidSimRequest {
+	raid := core.SinglePlayerRaidProto(settings.Player, settings.PartyBuffs, settings.RaidBuffs, settings.Debuffs)
+	raid.Tanks = settings.Tanks
+	raid.TargetDummies = settings.TargetDummies
+
+	return &proto.RaidSimRequest{
+		Raid:       raid,
+		Encounter:  settings.Encounter,
+		SimOptions: simOptions(settings.Settings),
+	}
+}
+
+func raidSimRequest(settings *proto.RaidSimSettings) *proto.RaidSimRequest {
+	raid := settings.Raid
+	if raid == nil {
+		raid = &proto.Raid{}
+	}
+	applyBlessings(raid, settings.Blessings)
+
+	return &proto.RaidSimRequest{
+		Raid:       raid,
+		Encounter:  settings.Encounter,
+		SimOptions: simOptions(settings.Settings),
+	}
+}
+
+func simOptions(settings *proto.SimSettings) *proto.SimOptions {
+	iterations := settings.GetIterations()
+	if iterations == 0 {
+		iterations = defaultIterations
+	}
+	seed := settings.GetFixedRngSeed()
+	if seed == 0 {
+		seed = rand.Int63n(maxRngSeed)
+	}
+
+	return &proto.SimOptions{
+		Iterations: iterations,
+		RandomSeed: seed,
+	}
+}
+
+// applyBlessings hands out each paladin's assigned blessings to the players of
+// every spec, skipping assignments for paladins who aren't in the raid.
+func applyBlessings(raid *proto.Raid, assignments *proto.BlessingsAssignments) {
+	var players []*proto.Player
+	numPaladins := 0
+	for _, party := range raid.Parties {
+		for _, player := range party.Players {
+			if player.GetClass() == proto.Class_ClassUnknown || player.GetSpec() == nil {
+				continue
+			}
+			if player.Class == proto.Class_ClassPaladin {
+				numPaladins++
+			}
+			players = append(players, player)
+		}
+	}
+
+	for i, paladin := range assignments.GetPaladins() {
+		if i >= numPaladins {
+			break
+		}
+		for _, player := range players {
+			spec := int(core.PlayerProtoToSpec(player))
+			if spec >= len(paladin.Blessings) {
+				continue
+			}
+			if player.Buffs == nil {
+				player.Buffs = &proto.IndividualBuffs{}
+			}
+
+			switch paladin.Blessings[spec] {
+			case proto.Blessings_BlessingOfKings:
+				player.Buffs.BlessingOfKings = true
+			case proto.Blessings_BlessingOfMight:
+				player.Buffs.BlessingOfMight = proto.TristateEffect_TristateEffectImproved
+			case proto.Blessings_BlessingOfWisdom:
+				player.Buffs.BlessingOfWisdom = proto.TristateEffect_TristateEffectImproved
+			case proto.Blessings_BlessingOfSanctuary:
+				player.Buffs.BlessingOfSanctuary = true
+			}
+		}
+	}
+}
+
+// settingsFromRaidSimRequest is the inverse of individualSimRequest and
+// raidSimRequest. Requests with a single player become IndividualSimSettings so
+// they open in that player's individual sim.
+func settingsFromRaidSimRequest(request *proto.RaidSimRequest) goproto.Message {
+	raid := request.Raid
+	if raid == nil {
+		raid = &proto.Raid{}
+	}
+	simSettings := &proto.SimSettings{
+		Iterations: request.SimOptions.GetIterations(),
+	}
+
+	var players []*proto.Player
+	var playerParty *proto.Party
+	for _, party := range raid.Parties {
+		for _, player := range party.Players {
+			if player.GetClass() != proto.Class_ClassUnknown {
+				players = append(players, player)
+				playerParty = party
+			}
+		}
+	}
+
+	if len(players) != 1 {
+		return &proto.RaidSimSettings{
+			Settings:  simSettings,
+			Raid:      raid,
+			Encounter: request.Encounter,
+		}
+	}
+
+	return &proto.IndividualSimSettings{
+		Settings:      simSettings,
+		RaidBuffs:     raid.Buffs,
+		Debuffs:       raid.Debuffs,
+		Tanks:         raid.Tanks,
+		PartyBuffs:    playerParty.Buffs,
+		Player:        players[0],
+		Encounter:     request.Encounter,
+		TargetDummies: raid.TargetDummies,
+	}
+}
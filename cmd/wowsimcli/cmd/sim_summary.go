@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/wowsims/sod/sim/core"
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+type playerSummary struct {
+	Name     string
+	Dps      float64
+	DpsStdev float64
+	Tps      float64
+	Dtps     float64
+	Actions  []actionSummary
+	Auras    []auraSummary
+}
+
+type actionSummary struct {
+	Name string
+	Dps  float64
+}
+
+type auraSummary struct {
+	Name   string
+	Uptime float64 // Percentage of the average iteration the aura was active.
+}
+
+// summarizeRaidSim condenses result into per-player metrics, keeping the top
+// actions by damage and the top auras by uptime for each player.
+func summarizeRaidSim(result *proto.RaidSimResult, iterations int32, top int) []playerSummary {
+	if result.IterationsDone > 0 {
+		iterations = result.IterationsDone
+	}
+	duration := result.AvgIterationDuration
+
+	var summaries []playerSummary
+	for _, party := range result.GetRaidMetrics().GetParties() {
+		for _, player := range party.Players {
+			// Empty party slots are filled with blank metrics.
+			if player.Dps == nil {
+				continue
+			}
+
+			summary := playerSummary{
+				Name:     player.Name,
+				Dps:      player.Dps.Avg,
+				DpsStdev: player.Dps.Stdev,
+				Tps:      player.GetThreat().GetAvg(),
+				Dtps:     player.GetDtps().GetAvg(),
+			}
+
+			for _, action := range player.Actions {
+				damage := 0.0
+				for _, target := range action.Targets {
+					damage += target.Damage
+				}
+				if damage == 0 || iterations == 0 || duration == 0 {
+					continue
+				}
+				summary.Actions = append(summary.Actions, actionSummary{
+					Name: actionName(action.Id),
+					Dps:  damage / float64(iterations) / duration,
+				})
+			}
+			slices.SortStableFunc(summary.Actions, func(a, b actionSummary) int {
+				return compareDesc(a.Dps, b.Dps)
+			})
+
+			for _, aura := range player.Auras {
+				if aura.UptimeSecondsAvg == 0 || duration == 0 {
+					continue
+				}
+				summary.Auras = append(summary.Auras, auraSummary{
+					Name:   actionName(aura.Id),
+					Uptime: aura.UptimeSecondsAvg / duration * 100,
+				})
+			}
+			slices.SortStableFunc(summary.Auras, func(a, b auraSummary) int {
+				return compareDesc(a.Uptime, b.Uptime)
+			})
+
+			summary.Actions = summary.Actions[:min(top, len(summary.Actions))]
+			summary.Auras = summary.Auras[:min(top, len(summary.Auras))]
+			summaries = append(summaries, summary)
+		}
+	}
+	return summaries
+}
+
+func compareDesc(a, b float64) int {
+	switch {
+	case a > b:
+		return -1
+	case a < b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// actionName prefers the item name for item actions, as spell names aren't
+// part of the sim database.
+func actionName(id *proto.ActionID) string {
+	if item, ok := core.ItemsByID[id.GetItemId()]; ok && id.GetItemId() != 0 {
+		return item.Name
+	}
+	return core.ProtoToActionID(id).String()
+}
+
+func printSummaryTable(summaries []playerSummary) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "Player\tDPS\tStdev\tTPS\tDTPS")
+	for _, summary := range summaries {
+		fmt.Fprintf(w, "%s\t%0.1f\t%0.1f\t%0.1f\t%0.1f\n", summary.Name, summary.Dps, summary.DpsStdev, summary.Tps, summary.Dtps)
+	}
+
+	for _, summary := range summaries {
+		fmt.Fprintf(w, "\n%s\n", summary.Name)
+		if len(summary.Actions) > 0 {
+			fmt.Fprintln(w, "  Action\tDPS")
+			for _, action := range summary.Actions {
+				fmt.Fprintf(w, "  %s\t%0.1f\n", action.Name, action.Dps)
+			}
+		}
+		if len(summary.Auras) > 0 {
+			fmt.Fprintln(w, "  Aura\tUptime")
+			for _, aura := range summary.Auras {
+				fmt.Fprintf(w, "  %s\t%0.1f%%\n", aura.Name, aura.Uptime)
+			}
+		}
+	}
+
+	w.Flush()
+	return buf.String()
+}
+
+// printSummaryCsv writes one row per metric, so every player, action and aura
+// shares the same columns.
+func printSummaryCsv(summaries []playerSummary) string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	formatFloat := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	}
+
+	w.Write([]string{"player", "metric", "name", "value"})
+	for _, summary := range summaries {
+		w.Write([]string{summary.Name, "dps", "", formatFloat(summary.Dps)})
+		w.Write([]string{summary.Name, "dps_stdev", "", formatFloat(summary.DpsStdev)})
+		w.Write([]string{summary.Name, "tps", "", formatFloat(summary.Tps)})
+		w.Write([]string{summary.Name, "dtps", "", formatFloat(summary.Dtps)})
+		for _, action := range summary.Actions {
+			w.Write([]string{summary.Name, "action_dps", action.Name, formatFloat(action.Dps)})
+		}
+		for _, aura := range summary.Auras {
+			w.Write([]string{summary.Name, "aura_uptime", aura.Name, formatFloat(aura.Uptime)})
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Fatalf("failed to write csv output: %s", err)
+	}
+	return buf.String()
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wowsims/sod/sim/core"
+	"github.com/wowsims/sod/sim/core/proto"
+	"github.com/wowsims/sod/sim/core/stats"
+)
+
+var weightsCmd = &cobra.Command{
+	Use:   "weights",
+	Short: "compute stat weights and EP values",
+	Long:  "compute stat weights and EP values, with standard deviations, for the requested stats",
+	Run:   weightsMain,
+}
+
+func init() {
+	weightsCmd.Flags().StringVar(&infile, "infile", "input.json", "location of input file (StatWeightsRequest in protojson format)")
+	weightsCmd.Flags().StringVar(&outfile, "outfile", "", "location of output file, defaults to stdout")
+	weightsCmd.Flags().StringVar(&format, "format", "json", "output format of the results, json or csv")
+	weightsCmd.Flags().BoolVar(&verbose, "verbose", false, "print information during runtime")
+	weightsCmd.MarkFlagRequired("infile")
+}
+
+func weightsMain(cmd *cobra.Command, args []string) {
+	input := &proto.StatWeightsRequest{}
+	loadProtoJson(infile, input)
+
+	progress := make(chan *proto.ProgressMetrics, 100)
+	core.StatWeightsAsync(input, progress, "cmd-stat-weights")
+
+	startTime := time.Now()
+
+	var result *proto.StatWeightsResult
+	for status := range progress {
+		if status.FinalWeightResult != nil {
+			result = status.FinalWeightResult
+			break
+		}
+		if verbose {
+			printProgress(status, startTime)
+		}
+	}
+
+	if result == nil {
+		log.Fatalf("stat weights finished without a result")
+	}
+	if result.Error != nil {
+		log.Fatalf("stat weights failed: %s", result.Error.Message)
+	}
+
+	switch format {
+	case "csv":
+		writeOutput(outfile, []byte(printWeights(input, result)))
+	case "json":
+		writeOutput(outfile, marshalProtoJson(result))
+	default:
+		log.Fatalf("unknown output format %q, expected json or csv", format)
+	}
+}
+
+// printWeights formats result as CSV with one row per weighed stat and metric.
+func printWeights(input *proto.StatWeightsRequest, result *proto.StatWeightsResult) string {
+	metrics := []struct {
+		name   string
+		values *proto.StatWeightValues
+	}{
+		{"dps", result.Dps},
+		{"hps", result.Hps},
+		{"tps", result.Tps},
+		{"dtps", result.Dtps},
+		{"tmi", result.Tmi},
+		{"p_death", result.PDeath},
+	}
+
+	output := "stat,metric,weight,weight_stdev,ep,ep_stdev\n"
+	for _, metric := range metrics {
+		if metric.values == nil {
+			continue
+		}
+		values := metric.values
+		for _, stat := range input.StatsToWeigh {
+			idx := int(stat)
+			output += fmt.Sprintf("%s,%s,%0.4f,%0.4f,%0.4f,%0.4f\n", stats.Stat(stat).StatName(), metric.name,
+				statValue(values.Weights.GetStats(), idx), statValue(values.WeightsStdev.GetStats(), idx),
+				statValue(values.EpValues.GetStats(), idx), statValue(values.EpValuesStdev.GetStats(), idx))
+		}
+		for _, pseudoStat := range input.PseudoStatsToWeigh {
+			idx := int(pseudoStat)
+			output += fmt.Sprintf("%s,%s,%0.4f,%0.4f,%0.4f,%0.4f\n", pseudoStat.String(), metric.name,
+				statValue(values.Weights.GetPseudoStats(), idx), statValue(values.WeightsStdev.GetPseudoStats(), idx),
+				statValue(values.EpValues.GetPseudoStats(), idx), statValue(values.EpValuesStdev.GetPseudoStats(), idx))
+		}
+	}
+	return output
+}
+
+func statValue(values []float64, idx int) float64 {
+	if idx < 0 || idx >= len(values) {
+		return 0
+	}
+	return values[idx]
+}
@@ -85,6 +85,52 @@ func RunRaidSimAsync(request *proto.RaidSimRequest, progress chan *proto.Progres
 	}()
 }
 
+/**
+ * Re-runs the single iteration with the given seed, with full debug logs.
+ */
+func ReplayIteration(request *proto.ReplayIterationRequest) *proto.ReplayIterationResult {
+	return replayIteration(request, simsignals.CreateSignals())
+}
+
+/**
+ * Runs the base and variant requests with the same per-iteration seeds, and compares their DPS iteration by iteration.
+ */
+func CompareSims(request *proto.CompareSimsRequest) *proto.CompareSimsResult {
+	return compareSims(request, simsignals.CreateSignals())
+}
+
+/**
+ * Returns the text format of an APL rotation, which is easier to read and diff than its JSON.
+ */
+func FormatAPLRotationText(request *proto.FormatAPLRotationRequest) *proto.FormatAPLRotationResult {
+	return &proto.FormatAPLRotationResult{
+		Text: FormatAPLRotation(request.Rotation),
+	}
+}
+
+/**
+ * Parses the text format of an APL rotation.
+ */
+func ParseAPLRotationText(request *proto.ParseAPLRotationRequest) *proto.ParseAPLRotationResult {
+	rotation, err := ParseAPLRotation(request.Text)
+	if err != nil {
+		return &proto.ParseAPLRotationResult{
+			Error: &proto.ErrorOutcome{Message: err.Error()},
+		}
+	}
+	return &proto.ParseAPLRotationResult{
+		Rotation: rotation,
+	}
+}
+
+/**
+ * Builds the environment of a player with an APL rotation, and returns the validation warnings of every entry of the
+ * rotation, along with static problems such as unreachable entries, so rotations can be checked without the UI.
+ */
+func LintAPLRotation(request *proto.LintAPLRotationRequest) *proto.LintAPLRotationResult {
+	return lintAPLRotation(request)
+}
+
 // Threading does not work in WASM!
 func RunRaidSimConcurrent(request *proto.RaidSimRequest) *proto.RaidSimResult {
 	return runSimConcurrent(request, nil, simsignals.CreateSignals())
@@ -14,6 +14,13 @@ type APLRotation struct {
 	prepullActions []*APLAction
 	priorityList   []*APLAction
 
+	// Index of each prepull action and priority list action in the config, for attributing warnings.
+	prepullConfigIdxs      []int
+	priorityListConfigIdxs []int
+
+	// Named lists evaluated by APLActionCallList and APLActionRunList.
+	actionLists []*APLActionList
+
 	// Action currently controlling this rotation (only used for certain actions, such as StrictSequence).
 	controllingActions []APLActionImpl
 
@@ -34,11 +41,21 @@ type APLRotation struct {
 	// Used to avoid recursive APL loops.
 	inLoop bool
 
+	// Variables set by APLActionSetVariable, by name.
+	variables map[string]*APLVariable
+
+	// Runtime metrics of each entry, only collected if SimOptions.AplMetrics is set.
+	metrics        *APLMetrics
+	collectMetrics bool
+	// Entries which led to the action returned by the last getNextAction().
+	selectedEntries []*aplEntryMetrics
+
 	// Validation warnings that occur during proto parsing.
 	// We return these back to the user for display in the UI.
 	curWarnings          []string
 	prepullWarnings      [][]string
 	priorityListWarnings [][]string
+	actionListWarnings   [][][]string
 }
 
 func (rot *APLRotation) ValidationWarning(message string, vals ...interface{}) {
@@ -80,7 +97,10 @@ func (unit *Unit) newAPLRotation(config *proto.APLRotation) *APLRotation {
 		unit:                 unit,
 		prepullWarnings:      make([][]string, len(config.PrepullActions)),
 		priorityListWarnings: make([][]string, len(config.PriorityList)),
+		actionListWarnings:   make([][][]string, len(config.ActionLists)),
+		metrics:              newAPLMetrics(config),
 	}
+	rotation.registerVariables(config)
 
 	// Parse prepull actions
 	for i, prepullItem := range config.PrepullActions {
@@ -95,8 +115,14 @@ func (unit *Unit) newAPLRotation(config *proto.APLRotation) *APLRotation {
 					} else {
 						action := rotation.newAPLAction(prepullItem.Action)
 						if action != nil {
+							action.metrics = rotation.metrics.prepullActions[prepullIdx]
 							rotation.prepullActions = append(rotation.prepullActions, action)
+							rotation.prepullConfigIdxs = append(rotation.prepullConfigIdxs, prepullIdx)
 							unit.RegisterPrepullAction(doAt, func(sim *Simulation) {
+								if rotation.collectMetrics {
+									action.metrics.addEvaluation(true)
+									action.metrics.addExecution(sim)
+								}
 								// Warnings for prepull cast failure are detected by running a fake prepull,
 								// so this action.Execute needs to record warnings.
 								rotation.doAndRecordWarnings(&rotation.prepullWarnings[prepullIdx], true, func() {
@@ -111,30 +137,91 @@ func (unit *Unit) newAPLRotation(config *proto.APLRotation) *APLRotation {
 	}
 
 	// Parse priority list
-	var configIdxs []int
 	for i, aplItem := range config.PriorityList {
 		rotation.doAndRecordWarnings(&rotation.priorityListWarnings[i], false, func() {
 			if !aplItem.Hide {
 				action := rotation.newAPLAction(aplItem.Action)
 				if action != nil {
+					action.metrics = rotation.metrics.priorityList[i]
 					rotation.priorityList = append(rotation.priorityList, action)
-					configIdxs = append(configIdxs, i)
+					rotation.priorityListConfigIdxs = append(rotation.priorityListConfigIdxs, i)
 				}
 			}
 		})
 	}
 
+	// Parse action lists. Lists are registered before their actions are finalized, so lists can call
+	// lists defined after them.
+	listsByConfigIdx := make([]*APLActionList, len(config.ActionLists))
+	for i, listConfig := range config.ActionLists {
+		rotation.actionListWarnings[i] = make([][]string, len(listConfig.Items))
+		list := &APLActionList{
+			name:      listConfig.Name,
+			configIdx: i,
+		}
+		if list.name == "" || rotation.getActionList(list.name) != nil {
+			if len(listConfig.Items) > 0 {
+				rotation.doAndRecordWarnings(&rotation.actionListWarnings[i][0], false, func() {
+					rotation.ValidationWarning("Action lists must have a unique, non-empty name: '%s'", list.name)
+				})
+			}
+			continue
+		}
+
+		for j, aplItem := range listConfig.Items {
+			rotation.doAndRecordWarnings(&rotation.actionListWarnings[i][j], false, func() {
+				if !aplItem.Hide {
+					action := rotation.newAPLAction(aplItem.Action)
+					if action != nil {
+						action.metrics = rotation.metrics.actionLists[i][j]
+						list.actions = append(list.actions, action)
+						list.configIdxs = append(list.configIdxs, j)
+					}
+				}
+			})
+		}
+		rotation.actionLists = append(rotation.actionLists, list)
+		listsByConfigIdx[i] = list
+	}
+
 	// Finalize
 	for i, action := range rotation.prepullActions {
-		rotation.doAndRecordWarnings(&rotation.prepullWarnings[i], true, func() {
+		rotation.doAndRecordWarnings(&rotation.prepullWarnings[rotation.prepullConfigIdxs[i]], true, func() {
 			action.Finalize(rotation)
 		})
 	}
 	for i, action := range rotation.priorityList {
-		rotation.doAndRecordWarnings(&rotation.priorityListWarnings[i], false, func() {
+		rotation.doAndRecordWarnings(&rotation.priorityListWarnings[rotation.priorityListConfigIdxs[i]], false, func() {
 			action.Finalize(rotation)
 		})
 	}
+	for i, list := range listsByConfigIdx {
+		if list == nil {
+			continue
+		}
+		for j, action := range list.actions {
+			rotation.doAndRecordWarnings(&rotation.actionListWarnings[i][list.configIdxs[j]], false, func() {
+				action.Finalize(rotation)
+			})
+		}
+	}
+
+	// Cycles are cut at runtime by skipping lists which are already being evaluated, but are
+	// most likely a mistake.
+	for i, list := range listsByConfigIdx {
+		if list == nil {
+			continue
+		}
+		for j, action := range list.actions {
+			for _, called := range action.getCalledLists() {
+				if called.reaches(list, make(map[*APLActionList]bool)) {
+					rotation.doAndRecordWarnings(&rotation.actionListWarnings[i][list.configIdxs[j]], false, func() {
+						rotation.ValidationWarning("List '%s' leads back to list '%s', which will be skipped while it is being evaluated", called.name, list.name)
+					})
+				}
+			}
+		}
+	}
 
 	// Remove MCDs that are referenced by APL actions, so that the Autocast Other Cooldowns
 	// action does not include them.
@@ -156,12 +243,21 @@ func (rot *APLRotation) getStats() *proto.APLStats {
 	return &proto.APLStats{
 		PrepullActions: MapSlice(rot.prepullWarnings, func(warnings []string) *proto.APLActionStats { return &proto.APLActionStats{Warnings: warnings} }),
 		PriorityList:   MapSlice(rot.priorityListWarnings, func(warnings []string) *proto.APLActionStats { return &proto.APLActionStats{Warnings: warnings} }),
+		ActionLists: MapSlice(rot.actionListWarnings, func(listWarnings [][]string) *proto.APLListStats {
+			return &proto.APLListStats{
+				Items: MapSlice(listWarnings, func(warnings []string) *proto.APLActionStats { return &proto.APLActionStats{Warnings: warnings} }),
+			}
+		}),
 	}
 }
 
 // Returns all action objects as an unstructured list. Used for easily finding specific actions.
 func (rot *APLRotation) allAPLActions() []*APLAction {
-	return Flatten(MapSlice(rot.priorityList, func(action *APLAction) []*APLAction { return action.GetAllActions() }))
+	actions := Flatten(MapSlice(rot.priorityList, func(action *APLAction) []*APLAction { return action.GetAllActions() }))
+	for _, list := range rot.actionLists {
+		actions = append(actions, Flatten(MapSlice(list.actions, func(action *APLAction) []*APLAction { return action.GetAllActions() }))...)
+	}
+	return actions
 }
 
 // Returns all action objects from the prepull as an unstructured list. Used for easily finding specific actions.
@@ -174,6 +270,12 @@ func (rot *APLRotation) reset(sim *Simulation) {
 	rot.inLoop = false
 	rot.interruptChannelIf = nil
 	rot.allowChannelRecastOnInterrupt = false
+	for _, variable := range rot.variables {
+		variable.reset()
+	}
+	for _, list := range rot.actionLists {
+		list.inLoop = false
+	}
 
 	rot.allowCastWhileChanneling = slices.ContainsFunc(rot.unit.Spellbook, func(spell *Spell) bool {
 		return spell.Flags.Matches(SpellFlagCastWhileChanneling)
@@ -215,6 +317,16 @@ func (apl *APLRotation) DoNextAction(sim *Simulation) {
 			panic(fmt.Sprintf("[USER_ERROR] Infinite loop detected, current action:\n%s", nextAction))
 		}
 
+		if apl.collectMetrics {
+			// Executing the action can evaluate lists again, e.g. for a Call List inside a sequence.
+			selectedEntries := apl.selectedEntries
+			nextAction.Execute(sim)
+			for _, entry := range selectedEntries {
+				entry.addExecution(sim)
+			}
+			continue
+		}
+
 		nextAction.Execute(sim)
 	}
 	apl.inLoop = false
@@ -230,17 +342,15 @@ func (apl *APLRotation) DoNextAction(sim *Simulation) {
 }
 
 func (apl *APLRotation) getNextAction(sim *Simulation) *APLAction {
+	// Actions returned by a controlling action are part of an entry which already counted its execution,
+	// so only actions selected by nextActionInList have selected entries.
+	apl.selectedEntries = nil
 	if len(apl.controllingActions) != 0 {
 		return apl.controllingActions[len(apl.controllingActions)-1].GetNextAction(sim)
 	}
 
-	for _, action := range apl.priorityList {
-		if action.IsReady(sim) {
-			return action
-		}
-	}
-
-	return nil
+	nextAction, _ := apl.nextActionInList(sim, apl.priorityList)
+	return nextAction
 }
 
 func (apl *APLRotation) pushControllingAction(ca APLActionImpl) {
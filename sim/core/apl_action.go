@@ -9,6 +9,9 @@ import (
 type APLAction struct {
 	condition APLValue
 	impl      APLActionImpl
+
+	// Set for the entries of prepull actions, the priority list and action lists.
+	metrics *aplEntryMetrics
 }
 
 func (action *APLAction) Finalize(rot *APLRotation) {
@@ -191,6 +194,14 @@ func (rot *APLRotation) newAPLActionImpl(config *proto.APLAction) APLActionImpl
 		return rot.newActionCustomRotation(config.GetCustomRotation())
 	case *proto.APLAction_AddComboPoints:
 		return rot.newActionAddComboPoints(config.GetAddComboPoints())
+	case *proto.APLAction_SetVariable:
+		return rot.newActionSetVariable(config.GetSetVariable())
+
+	// Action lists
+	case *proto.APLAction_CallList:
+		return rot.newActionCallList(config.GetCallList())
+	case *proto.APLAction_RunList:
+		return rot.newActionRunList(config.GetRunList())
 	default:
 		return nil
 	}
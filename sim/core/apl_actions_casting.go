@@ -156,8 +156,7 @@ func (action *APLActionMultidot) IsReady(sim *Simulation) bool {
 			}
 		}
 	} else {
-		for i := int32(0); i < action.maxDots; i++ {
-			target := sim.Encounter.TargetUnits[i]
+		for _, target := range sim.Encounter.ActiveTargetUnits[:min(action.maxDots, int32(len(sim.Encounter.ActiveTargetUnits)))] {
 			dot := action.spell.Dot(target)
 			if (!dot.IsActive() || dot.RemainingDuration(sim) < maxOverlap) && action.spell.CanCast(sim, target) {
 				action.nextTarget = target
@@ -0,0 +1,203 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+// APLActionList is a named list of actions, evaluated by APLActionCallList and APLActionRunList.
+type APLActionList struct {
+	name    string
+	actions []*APLAction
+
+	// Index of the list and of each of its actions in the config, for attributing warnings.
+	configIdx  int
+	configIdxs []int
+
+	// Used to avoid cycles between lists.
+	inLoop bool
+}
+
+func (rot *APLRotation) getActionList(name string) *APLActionList {
+	for _, list := range rot.actionLists {
+		if list.name == name {
+			return list
+		}
+	}
+	return nil
+}
+
+// Returns the lists called by an action, including by its inner actions.
+func (action *APLAction) getCalledLists() []*APLActionList {
+	var lists []*APLActionList
+	for _, inner := range action.GetAllActions() {
+		if ref, ok := inner.impl.(aplActionListRef); ok && ref.getList() != nil {
+			lists = append(lists, ref.getList())
+		}
+	}
+	return lists
+}
+
+// Whether evaluating list eventually evaluates target.
+func (list *APLActionList) reaches(target *APLActionList, visited map[*APLActionList]bool) bool {
+	if list == target {
+		return true
+	}
+	if visited[list] {
+		return false
+	}
+	visited[list] = true
+	for _, action := range list.actions {
+		for _, called := range action.getCalledLists() {
+			if called.reaches(target, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the next action to perform from a list, and whether the evaluation stops at this list
+// instead of continuing with the rest of the calling list.
+func (rot *APLRotation) nextActionInList(sim *Simulation, actions []*APLAction) (*APLAction, bool) {
+	for _, action := range actions {
+		conditionMet := action.condition == nil || action.condition.GetBool(sim)
+		if rot.collectMetrics && action.metrics != nil {
+			action.metrics.addEvaluation(conditionMet)
+		}
+		if !conditionMet {
+			continue
+		}
+
+		// Setting a variable takes no time, so it happens during the evaluation, which then continues
+		// with the next action. Otherwise an unconditional Set Variable would be selected forever.
+		if setVariable, ok := action.impl.(*APLActionSetVariable); ok {
+			setVariable.Execute(sim)
+			if rot.collectMetrics && action.metrics != nil {
+				action.metrics.addExecution(sim)
+			}
+			continue
+		}
+
+		var next *APLAction
+		if ref, ok := action.impl.(aplActionListRef); !ok {
+			if !action.impl.IsReady(sim) {
+				continue
+			}
+			next = action
+		} else if subNext, stop := ref.nextAction(sim); subNext != nil || stop {
+			next = subNext
+		} else {
+			continue
+		}
+
+		if rot.collectMetrics && action.metrics != nil && next != nil {
+			rot.selectedEntries = append(rot.selectedEntries, action.metrics)
+		}
+		return next, true
+	}
+	return nil, false
+}
+
+type aplActionListRef interface {
+	getList() *APLActionList
+	nextAction(sim *Simulation) (*APLAction, bool)
+}
+
+// Shared implementation of the call and run list actions.
+type aplActionListCall struct {
+	defaultAPLActionImpl
+	rot       *APLRotation
+	name      string
+	list      *APLActionList
+	exclusive bool
+
+	// The action selected by IsReady, which Execute performs without evaluating the list again.
+	// Evaluating a list can set variables and records metrics, so it happens once per decision.
+	selected   *APLAction
+	selectedAt time.Duration
+}
+
+func (rot *APLRotation) newActionListCall(name string, exclusive bool) *aplActionListCall {
+	if name == "" {
+		rot.ValidationWarning("Must provide an action list name")
+		return nil
+	}
+	return &aplActionListCall{
+		rot:       rot,
+		name:      name,
+		exclusive: exclusive,
+	}
+}
+func (action *aplActionListCall) Finalize(rot *APLRotation) {
+	action.list = rot.getActionList(action.name)
+	if action.list == nil {
+		rot.ValidationWarning("No action list with name: '%s'", action.name)
+	}
+}
+func (action *aplActionListCall) getList() *APLActionList {
+	return action.list
+}
+
+// Cycles between lists are cut by skipping lists which are already being evaluated.
+func (action *aplActionListCall) nextAction(sim *Simulation) (*APLAction, bool) {
+	if action.list == nil || action.list.inLoop {
+		return nil, false
+	}
+
+	action.list.inLoop = true
+	next, stop := action.rot.nextActionInList(sim, action.list.actions)
+	action.list.inLoop = false
+
+	return next, stop || action.exclusive
+}
+func (action *aplActionListCall) Reset(*Simulation) {
+	action.selected = nil
+}
+func (action *aplActionListCall) IsReady(sim *Simulation) bool {
+	action.selected, _ = action.nextAction(sim)
+	action.selectedAt = sim.CurrentTime
+	return action.selected != nil
+}
+func (action *aplActionListCall) Execute(sim *Simulation) {
+	next := action.selected
+	if next == nil || action.selectedAt != sim.CurrentTime {
+		next, _ = action.nextAction(sim)
+	}
+	action.selected = nil
+	if next != nil {
+		next.Execute(sim)
+	}
+}
+
+type APLActionCallList struct {
+	*aplActionListCall
+}
+
+func (rot *APLRotation) newActionCallList(config *proto.APLActionCallList) APLActionImpl {
+	call := rot.newActionListCall(config.ListName, false)
+	if call == nil {
+		return nil
+	}
+	return &APLActionCallList{call}
+}
+func (action *APLActionCallList) String() string {
+	return fmt.Sprintf("Call List(%s)", action.name)
+}
+
+type APLActionRunList struct {
+	*aplActionListCall
+}
+
+func (rot *APLRotation) newActionRunList(config *proto.APLActionRunList) APLActionImpl {
+	call := rot.newActionListCall(config.ListName, true)
+	if call == nil {
+		return nil
+	}
+	return &APLActionRunList{call}
+}
+func (action *APLActionRunList) String() string {
+	return fmt.Sprintf("Run List(%s)", action.name)
+}
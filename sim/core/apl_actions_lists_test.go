@@ -0,0 +1,117 @@
+package core
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/wowsims/sod/sim/core/proto"
+	"github.com/wowsims/sod/sim/core/simsignals"
+)
+
+func actionListsTestRequest(exclusive bool) *proto.RaidSimRequest {
+	rsr := fakeSimRequest()
+
+	callList := func(name string) *proto.APLAction {
+		if exclusive {
+			return &proto.APLAction{Action: &proto.APLAction_RunList{RunList: &proto.APLActionRunList{ListName: name}}}
+		}
+		return &proto.APLAction{Action: &proto.APLAction_CallList{CallList: &proto.APLActionCallList{ListName: name}}}
+	}
+
+	fakeDot := &proto.ActionID{RawId: &proto.ActionID_SpellId{SpellId: 42}}
+	rotation := &proto.APLRotation{Type: proto.APLRotation_TypeAPL}
+	rsr.Raid.Parties[0].Players[0].Rotation = rotation
+	rotation.PriorityList = []*proto.APLListItem{
+		{Action: &proto.APLAction{Action: &proto.APLAction_CallList{CallList: &proto.APLActionCallList{ListName: "ping"}}}},
+		{Action: callList("dot")},
+		{Action: &proto.APLAction{Action: &proto.APLAction_SetVariable{SetVariable: &proto.APLActionSetVariable{
+			Name:       "last evaluated",
+			IsDuration: true,
+			Value:      &proto.APLValue{Value: &proto.APLValue_CurrentTime{CurrentTime: &proto.APLValueCurrentTime{}}},
+		}}}},
+	}
+	rotation.ActionLists = []*proto.APLActionList{
+		{Name: "dot", Items: []*proto.APLListItem{
+			{Action: &proto.APLAction{
+				Condition: &proto.APLValue{Value: &proto.APLValue_Not{Not: &proto.APLValueNot{
+					Val: &proto.APLValue{Value: &proto.APLValue_DotIsActive{DotIsActive: &proto.APLValueDotIsActive{SpellId: fakeDot}}},
+				}}},
+				Action: &proto.APLAction_CastSpell{CastSpell: &proto.APLActionCastSpell{SpellId: fakeDot}},
+			}},
+		}},
+		{Name: "ping", Items: []*proto.APLListItem{
+			{Action: &proto.APLAction{Action: &proto.APLAction_CallList{CallList: &proto.APLActionCallList{ListName: "pong"}}}},
+		}},
+		{Name: "pong", Items: []*proto.APLListItem{
+			{Action: &proto.APLAction{Action: &proto.APLAction_CallList{CallList: &proto.APLActionCallList{ListName: "ping"}}}},
+			{Action: &proto.APLAction{Action: &proto.APLAction_RunList{RunList: &proto.APLActionRunList{ListName: "missing"}}}},
+		}},
+	}
+	return rsr
+}
+
+func TestActionLists(t *testing.T) {
+	for _, exclusive := range []bool{false, true} {
+		rsr := actionListsTestRequest(exclusive)
+		sim := NewSim(rsr, simsignals.CreateSignals())
+		character := sim.Raid.Parties[0].Players[0].GetCharacter()
+		spell := character.GetSpell(ActionID{SpellID: 42})
+
+		// A called list falls through to the next action when none of its actions are ready,
+		// while a run list never does.
+		startFakeSimUntil(sim, time.Second*30)
+		if spell.SpellMetrics[0].Casts == 0 {
+			t.Errorf("Expected the dot to be cast from its list")
+		}
+		last := character.Rotation.variables["last evaluated"].duration
+		if exclusive && last != 0 {
+			t.Errorf("Expected Run List to never reach the following actions, last reached at %s", last)
+		} else if !exclusive && last <= 0 {
+			t.Errorf("Expected Call List to fall through to the following actions")
+		}
+
+		stats := character.Rotation.getStats()
+		if len(stats.ActionLists) != 3 || len(stats.ActionLists[2].Items) != 2 {
+			t.Fatalf("Expected warnings for each item of each list, got %v", stats.ActionLists)
+		}
+		if len(stats.ActionLists[0].Items[0].Warnings) != 0 {
+			t.Errorf("Expected no warnings for the dot list, got %v", stats.ActionLists[0].Items[0].Warnings)
+		}
+		if len(stats.ActionLists[1].Items[0].Warnings) == 0 || len(stats.ActionLists[2].Items[0].Warnings) == 0 {
+			t.Errorf("Expected warnings for the cycle between lists")
+		}
+		if !slices.Contains(stats.ActionLists[2].Items[1].Warnings, "No action list with name: 'missing'") {
+			t.Errorf("Expected a warning for the missing list, got %v", stats.ActionLists[2].Items[1].Warnings)
+		}
+	}
+}
+
+func TestActionListInSequenceEvaluatedOnce(t *testing.T) {
+	rotation, err := ParseAPLRotation(`
+priority:
+	sequence(actions=[call_list("once")])
+
+list "once":
+	set_variable("n", value=variable("n") + 1)
+	cast_spell(spell:42) if variable("n") == 1
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse: %s", err)
+	}
+	rsr := fakeSimRequest()
+	rsr.Raid.Parties[0].Players[0].Rotation = rotation
+	sim := NewSim(rsr, simsignals.CreateSignals())
+	character := sim.Raid.Parties[0].Players[0].GetCharacter()
+	spell := character.GetSpell(ActionID{SpellID: 42})
+
+	// The sequence checks whether the list is ready and then executes it, which must not evaluate
+	// the list a second time.
+	startFakeSimUntil(sim, time.Second*10)
+	if n := character.Rotation.variables["n"].number; n != 1 {
+		t.Errorf("Expected the variable to be set once, got %0.0f", n)
+	}
+	if casts := spell.SpellMetrics[0].Casts; casts != 1 {
+		t.Errorf("Expected 1 cast, got %d", casts)
+	}
+}
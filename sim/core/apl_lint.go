@@ -0,0 +1,156 @@
+package core
+
+import (
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"strings"
+
+	"github.com/wowsims/sod/sim/core/proto"
+	googleProto "google.golang.org/protobuf/proto"
+)
+
+// Builds the environment of a single player with the rotation, and returns all validation warnings
+// of the rotation along with the warnings of lint().
+func lintAPLRotation(request *proto.LintAPLRotationRequest) (result *proto.LintAPLRotationResult) {
+	defer func() {
+		if err := recover(); err != nil {
+			result = &proto.LintAPLRotationResult{
+				Error: &proto.ErrorOutcome{Message: fmt.Sprintf("%v\nStack Trace:\n%s", err, string(debug.Stack()))},
+			}
+		}
+	}()
+
+	if request.Player == nil {
+		return &proto.LintAPLRotationResult{
+			Error: &proto.ErrorOutcome{Message: "Must provide a player"},
+		}
+	}
+	player := googleProto.Clone(request.Player).(*proto.Player)
+	if request.Rotation != nil {
+		player.Rotation = request.Rotation
+	}
+	if player.Rotation == nil {
+		return &proto.LintAPLRotationResult{
+			Error: &proto.ErrorOutcome{Message: "Must provide a rotation"},
+		}
+	}
+
+	encounter := request.Encounter
+	if encounter == nil {
+		encounter = &proto.Encounter{}
+	}
+	env, _, _ := NewEnvironment(SinglePlayerRaidProto(player, request.PartyBuffs, request.RaidBuffs, request.Debuffs), encounter, true)
+	rot := env.Raid.Parties[0].Players[0].GetCharacter().Rotation
+	rot.lint()
+
+	result = &proto.LintAPLRotationResult{}
+	addWarnings := func(prepull bool, listName string, entryWarnings [][]string) {
+		for i, warnings := range entryWarnings {
+			for _, warning := range warnings {
+				result.Warnings = append(result.Warnings, &proto.APLLintWarning{
+					Prepull:  prepull,
+					ListName: listName,
+					Index:    int32(i),
+					Message:  warning,
+				})
+			}
+		}
+	}
+	addWarnings(true, "", rot.prepullWarnings)
+	addWarnings(false, "", rot.priorityListWarnings)
+	for i, listConfig := range player.Rotation.ActionLists {
+		addWarnings(false, listConfig.Name, rot.actionListWarnings[i])
+	}
+	return result
+}
+
+// Adds warnings for problems which don't stop a rotation from running, but are most likely mistakes.
+// These are only reported by LintAPLRotation, as they can be intended while a rotation is being edited.
+func (rot *APLRotation) lint() {
+	for i, action := range rot.prepullActions {
+		rot.doAndRecordWarnings(&rot.prepullWarnings[rot.prepullConfigIdxs[i]], true, func() {
+			rot.lintComparisons(action)
+		})
+	}
+	rot.lintList(rot.priorityList, rot.priorityListConfigIdxs, rot.priorityListWarnings)
+	for _, list := range rot.actionLists {
+		rot.lintList(list.actions, list.configIdxs, rot.actionListWarnings[list.configIdx])
+	}
+}
+
+func (rot *APLRotation) lintList(actions []*APLAction, configIdxs []int, warnings [][]string) {
+	var blockingAction *APLAction
+	for i, action := range actions {
+		rot.doAndRecordWarnings(&warnings[configIdxs[i]], false, func() {
+			if blockingAction != nil && !action.isOffGCDAfter(blockingAction) {
+				rot.ValidationWarning("Never evaluated, because %s above has no condition and is always ready", blockingAction.impl)
+			}
+			rot.lintComparisons(action)
+		})
+		if blockingAction == nil && action.endsEvaluation() {
+			blockingAction = action
+		}
+	}
+}
+
+// Returns whether evaluating this action always ends the evaluation of its list, whenever the
+// GCD is ready.
+func (action *APLAction) endsEvaluation() bool {
+	if action.condition != nil {
+		return false
+	}
+
+	switch impl := action.impl.(type) {
+	case *APLActionRunList:
+		return true
+	case *APLActionCastSpell:
+		spell := impl.spell
+		return spell.CD.Timer == nil && spell.SharedCD.Timer == nil && spell.Cost == nil && spell.ExtraCastCondition == nil
+	}
+	return false
+}
+
+// Returns whether this action only casts spells which are off the GCD, while the blocking action casts
+// a spell on the GCD. Such actions are still evaluated while the GCD is not ready.
+func (action *APLAction) isOffGCDAfter(blockingAction *APLAction) bool {
+	castSpell, ok := blockingAction.impl.(*APLActionCastSpell)
+	if !ok || castSpell.spell.DefaultCast.GCD == 0 {
+		return false
+	}
+
+	spells := action.GetAllSpells()
+	return len(spells) > 0 && !slices.ContainsFunc(spells, func(spell *Spell) bool { return spell.DefaultCast.GCD > 0 })
+}
+
+// Warns about comparisons of values with different types, e.g. a duration with a number, which are
+// coerced to the same type and so likely don't compare what was intended.
+func (rot *APLRotation) lintComparisons(action *APLAction) {
+	uncoerced := func(value APLValue) APLValue {
+		if coerced, ok := value.(*APLValueCoerced); ok {
+			return coerced.inner
+		}
+		return value
+	}
+	isNumber := func(valueType proto.APLValueType) bool {
+		return valueType == proto.APLValueType_ValueTypeInt || valueType == proto.APLValueType_ValueTypeFloat
+	}
+	typeName := func(valueType proto.APLValueType) string {
+		return strings.ToLower(strings.TrimPrefix(valueType.String(), "ValueType"))
+	}
+
+	for _, value := range action.GetAllAPLValues() {
+		cmp, ok := value.(*APLValueCompare)
+		if !ok {
+			continue
+		}
+		lhs, rhs := uncoerced(cmp.lhs), uncoerced(cmp.rhs)
+		// Constants are written without a type, so their coercion is expected.
+		_, lhsIsConst := lhs.(*APLValueConst)
+		_, rhsIsConst := rhs.(*APLValueConst)
+		if lhsIsConst || rhsIsConst || lhs.Type() == rhs.Type() || (isNumber(lhs.Type()) && isNumber(rhs.Type())) {
+			continue
+		}
+		rot.ValidationWarning("Comparing %s (%s) with %s (%s), which have different types", lhs, typeName(lhs.Type()), rhs, typeName(rhs.Type()))
+	}
+}
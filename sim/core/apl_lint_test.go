@@ -0,0 +1,69 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+func TestLintAPLRotation(t *testing.T) {
+	rotation, err := ParseAPLRotation(`
+priority:
+	hide cast_spell(spell:999)
+	cast_spell(spell:999)
+	cast_spell(spell:42) if current_time > number_targets
+	cast_spell(spell:42) if current_time > 5
+	cast_spell(spell:42)
+	cast_spell(spell:42) if aura_is_active(spell:12345)
+
+list "aoe":
+	run_list("single")
+	cast_spell(spell:42)
+
+list "single":
+	cast_spell(spell:42)
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse: %s", err)
+	}
+
+	rsr := fakeSimRequest()
+	result := LintAPLRotation(&proto.LintAPLRotationRequest{
+		Player:     rsr.Raid.Parties[0].Players[0],
+		PartyBuffs: rsr.Raid.Parties[0].Buffs,
+		Encounter:  rsr.Encounter,
+		Rotation:   rotation,
+	})
+	if result.Error != nil {
+		t.Fatalf("Lint failed: %s", result.Error.Message)
+	}
+
+	expected := []struct {
+		listName string
+		index    int32
+		message  string
+	}{
+		{"", 1, "does not know spell"},
+		{"", 2, "different types"},
+		{"", 5, "Never evaluated"},
+		{"", 5, "No aura found"},
+		{"aoe", 1, "Never evaluated"},
+	}
+	if len(result.Warnings) != len(expected) {
+		t.Fatalf("Expected %d warnings, got %v", len(expected), result.Warnings)
+	}
+	for _, exp := range expected {
+		found := false
+		for _, warning := range result.Warnings {
+			found = found || (!warning.Prepull && warning.ListName == exp.listName && warning.Index == exp.index && strings.Contains(warning.Message, exp.message))
+		}
+		if !found {
+			t.Errorf("Expected a warning containing %q for entry %d of list %q, got %v", exp.message, exp.index, exp.listName, result.Warnings)
+		}
+	}
+
+	if result := LintAPLRotation(&proto.LintAPLRotationRequest{Rotation: rotation}); result.Error == nil {
+		t.Errorf("Expected an error without a player")
+	}
+}
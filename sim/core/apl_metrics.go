@@ -0,0 +1,169 @@
+package core
+
+import (
+	"time"
+
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+// Runtime metrics of a prepull action, priority list item or action list item of a rotation.
+// Only collected if SimOptions.AplMetrics is set.
+type aplEntryMetrics struct {
+	// Reset at the start of each iteration.
+	evaluations    int32
+	conditionsMet  int32
+	executions     int32
+	firstExecution time.Duration
+
+	evaluationsSum        int64
+	conditionsMetSum      int64
+	executionsSum         int64
+	iterationsExecuted    int32
+	firstExecutionTimeSum float64
+}
+
+func (entry *aplEntryMetrics) addEvaluation(conditionMet bool) {
+	entry.evaluations++
+	if conditionMet {
+		entry.conditionsMet++
+	}
+}
+
+func (entry *aplEntryMetrics) addExecution(sim *Simulation) {
+	if entry.executions == 0 {
+		entry.firstExecution = sim.CurrentTime
+	}
+	entry.executions++
+}
+
+func (entry *aplEntryMetrics) reset() {
+	entry.evaluations = 0
+	entry.conditionsMet = 0
+	entry.executions = 0
+	entry.firstExecution = 0
+}
+
+func (entry *aplEntryMetrics) doneIteration() {
+	entry.evaluationsSum += int64(entry.evaluations)
+	entry.conditionsMetSum += int64(entry.conditionsMet)
+	entry.executionsSum += int64(entry.executions)
+	if entry.executions > 0 {
+		entry.iterationsExecuted++
+		entry.firstExecutionTimeSum += entry.firstExecution.Seconds()
+	}
+}
+
+func (entry *aplEntryMetrics) ToProto(numIterations float64) *proto.APLEntryMetrics {
+	entryProto := &proto.APLEntryMetrics{
+		EvaluationsAvg:   float64(entry.evaluationsSum) / numIterations,
+		ConditionsMetAvg: float64(entry.conditionsMetSum) / numIterations,
+		ExecutionsAvg:    float64(entry.executionsSum) / numIterations,
+		ChanceExecuted:   float64(entry.iterationsExecuted) / numIterations,
+	}
+	if entry.iterationsExecuted > 0 {
+		entryProto.FirstExecutionSecondsAvg = entry.firstExecutionTimeSum / float64(entry.iterationsExecuted)
+	}
+	return entryProto
+}
+
+// Metrics of all entries of a rotation, indexed like its config and APLStats, so hidden and
+// invalid entries have metrics which stay at 0.
+type APLMetrics struct {
+	prepullActions []*aplEntryMetrics
+	priorityList   []*aplEntryMetrics
+	actionLists    [][]*aplEntryMetrics
+}
+
+func newAPLMetrics(config *proto.APLRotation) *APLMetrics {
+	newEntries := func(n int) []*aplEntryMetrics {
+		entries := make([]*aplEntryMetrics, n)
+		for i := range entries {
+			entries[i] = &aplEntryMetrics{}
+		}
+		return entries
+	}
+
+	metrics := &APLMetrics{
+		prepullActions: newEntries(len(config.PrepullActions)),
+		priorityList:   newEntries(len(config.PriorityList)),
+		actionLists:    make([][]*aplEntryMetrics, len(config.ActionLists)),
+	}
+	for i, list := range config.ActionLists {
+		metrics.actionLists[i] = newEntries(len(list.Items))
+	}
+	return metrics
+}
+
+func (metrics *APLMetrics) allEntries() []*aplEntryMetrics {
+	entries := append(append([]*aplEntryMetrics{}, metrics.prepullActions...), metrics.priorityList...)
+	return append(entries, Flatten(metrics.actionLists)...)
+}
+
+func (metrics *APLMetrics) reset() {
+	for _, entry := range metrics.allEntries() {
+		entry.reset()
+	}
+}
+
+func (metrics *APLMetrics) doneIteration() {
+	for _, entry := range metrics.allEntries() {
+		entry.doneIteration()
+	}
+}
+
+func (metrics *APLMetrics) ToProto(numIterations float64) *proto.APLMetrics {
+	entriesToProto := func(entries []*aplEntryMetrics) []*proto.APLEntryMetrics {
+		return MapSlice(entries, func(entry *aplEntryMetrics) *proto.APLEntryMetrics { return entry.ToProto(numIterations) })
+	}
+	return &proto.APLMetrics{
+		PrepullActions: entriesToProto(metrics.prepullActions),
+		PriorityList:   entriesToProto(metrics.priorityList),
+		ActionLists: MapSlice(metrics.actionLists, func(entries []*aplEntryMetrics) *proto.APLListMetrics {
+			return &proto.APLListMetrics{Items: entriesToProto(entries)}
+		}),
+	}
+}
+
+// Starts collecting the APLMetrics of every unit with a rotation, if requested.
+func (sim *Simulation) initAPLMetrics() {
+	if !sim.Options.AplMetrics {
+		return
+	}
+
+	for _, unit := range sim.AllUnits {
+		if unit.Rotation != nil && unit.Metrics.apl == nil {
+			unit.Rotation.collectMetrics = true
+			unit.Metrics.apl = unit.Rotation.metrics
+		}
+	}
+}
+
+// Merges the APLMetrics of concurrent sims. The first execution time is weighted by the
+// chance of executing, as it is averaged over the iterations with an execution.
+func combineAPLMetrics(base *proto.APLMetrics, add *proto.APLMetrics, weight float64) {
+	combineEntries := func(baseEntries []*proto.APLEntryMetrics, addEntries []*proto.APLEntryMetrics) []*proto.APLEntryMetrics {
+		for len(baseEntries) < len(addEntries) {
+			baseEntries = append(baseEntries, &proto.APLEntryMetrics{})
+		}
+		for i, addEntry := range addEntries {
+			baseEntry := baseEntries[i]
+			if executed := baseEntry.ChanceExecuted + addEntry.ChanceExecuted*weight; executed > 0 {
+				baseEntry.FirstExecutionSecondsAvg = (baseEntry.FirstExecutionSecondsAvg*baseEntry.ChanceExecuted + addEntry.FirstExecutionSecondsAvg*addEntry.ChanceExecuted*weight) / executed
+			}
+			baseEntry.EvaluationsAvg += addEntry.EvaluationsAvg * weight
+			baseEntry.ConditionsMetAvg += addEntry.ConditionsMetAvg * weight
+			baseEntry.ExecutionsAvg += addEntry.ExecutionsAvg * weight
+			baseEntry.ChanceExecuted += addEntry.ChanceExecuted * weight
+		}
+		return baseEntries
+	}
+
+	base.PrepullActions = combineEntries(base.PrepullActions, add.PrepullActions)
+	base.PriorityList = combineEntries(base.PriorityList, add.PriorityList)
+	for len(base.ActionLists) < len(add.ActionLists) {
+		base.ActionLists = append(base.ActionLists, &proto.APLListMetrics{})
+	}
+	for i, addList := range add.ActionLists {
+		base.ActionLists[i].Items = combineEntries(base.ActionLists[i].Items, addList.Items)
+	}
+}
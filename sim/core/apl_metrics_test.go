@@ -0,0 +1,123 @@
+package core
+
+import (
+	"math"
+	"testing"
+
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+func TestAPLMetrics(t *testing.T) {
+	rsr := fakeSimRequest()
+	rsr.SimOptions.Iterations = 20
+	rsr.SimOptions.AplMetrics = true
+	rsr.Encounter.Duration = 60
+	rsr.Encounter.DurationVariation = 20
+	rsr.Raid.Parties[0].Players[0].Rotation = fakeDotRotation()
+
+	setVariable := func(name string) *proto.APLAction_SetVariable {
+		return &proto.APLAction_SetVariable{SetVariable: &proto.APLActionSetVariable{
+			Name:  name,
+			Value: &proto.APLValue{Value: &proto.APLValue_Const{Const: &proto.APLValueConst{Val: "1"}}},
+		}}
+	}
+	rotation := rsr.Raid.Parties[0].Players[0].Rotation
+	rotation.PrepullActions = []*proto.APLPrepullAction{
+		{
+			Action:    &proto.APLAction{Action: setVariable("prepull")},
+			DoAtValue: &proto.APLValue{Value: &proto.APLValue_Const{Const: &proto.APLValueConst{Val: "-1s"}}},
+		},
+	}
+	rotation.PriorityList = append(rotation.PriorityList,
+		&proto.APLListItem{Hide: true, Action: &proto.APLAction{Action: setVariable("hidden")}},
+		&proto.APLListItem{Action: &proto.APLAction{
+			Condition: &proto.APLValue{Value: &proto.APLValue_Cmp{Cmp: &proto.APLValueCompare{
+				Op:  proto.APLValueCompare_OpLt,
+				Lhs: &proto.APLValue{Value: &proto.APLValue_CurrentTime{CurrentTime: &proto.APLValueCurrentTime{}}},
+				Rhs: &proto.APLValue{Value: &proto.APLValue_Const{Const: &proto.APLValueConst{Val: "0s"}}},
+			}}},
+			Action: setVariable("never"),
+		}},
+	)
+
+	for _, concurrent := range []bool{false, true} {
+		var result *proto.RaidSimResult
+		if concurrent {
+			result = RunRaidSimConcurrent(rsr)
+		} else {
+			result = RunRaidSim(rsr)
+		}
+		if result.Error != nil {
+			t.Fatalf("Sim failed: %s", result.Error.Message)
+		}
+
+		player := result.RaidMetrics.Parties[0].Players[0]
+		metrics := player.AplMetrics
+		if metrics == nil || len(metrics.PrepullActions) != 1 || len(metrics.PriorityList) != 3 {
+			t.Fatalf("Expected metrics for each entry, got %v", metrics)
+		}
+
+		prepull := metrics.PrepullActions[0]
+		if prepull.ExecutionsAvg != 1 || prepull.ChanceExecuted != 1 || math.Abs(prepull.FirstExecutionSecondsAvg+1) > 1e-9 {
+			t.Errorf("Expected the prepull action to execute once at -1s, got %v", prepull)
+		}
+
+		var casts int32
+		for _, action := range player.Actions {
+			if action.Id.GetSpellId() == 42 {
+				casts += action.Targets[0].Casts
+			}
+		}
+		dot := metrics.PriorityList[0]
+		if expected := float64(casts) / float64(rsr.SimOptions.Iterations); math.Abs(dot.ExecutionsAvg-expected) > 1e-9 {
+			t.Errorf("Expected %0.2f dot executions per iteration, got %0.2f", expected, dot.ExecutionsAvg)
+		}
+		if dot.ChanceExecuted != 1 || dot.FirstExecutionSecondsAvg != 0 {
+			t.Errorf("Expected the dot to be cast at the start of each iteration, got %v", dot)
+		}
+		if dot.EvaluationsAvg < dot.ConditionsMetAvg || dot.ConditionsMetAvg < dot.ExecutionsAvg {
+			t.Errorf("Expected at least as many evaluations as met conditions, and met conditions as executions, got %v", dot)
+		}
+
+		if hidden := metrics.PriorityList[1]; hidden.EvaluationsAvg != 0 {
+			t.Errorf("Expected hidden entries to never be evaluated, got %v", hidden)
+		}
+		if never := metrics.PriorityList[2]; never.EvaluationsAvg == 0 || never.ConditionsMetAvg != 0 || never.ExecutionsAvg != 0 {
+			t.Errorf("Expected the last entry to be evaluated but never executed, got %v", never)
+		}
+	}
+}
+
+func TestAPLMetricsSequence(t *testing.T) {
+	rsr := fakeSimRequest()
+	rsr.SimOptions.Iterations = 5
+	rsr.SimOptions.AplMetrics = true
+	rsr.Encounter.Duration = 60
+
+	castDot := &proto.APLAction{Action: &proto.APLAction_CastSpell{CastSpell: &proto.APLActionCastSpell{
+		SpellId: &proto.ActionID{RawId: &proto.ActionID_SpellId{SpellId: 42}},
+	}}}
+	rotation := fakeDotRotation()
+	rotation.PriorityList[0].Action.Action = &proto.APLAction_StrictSequence{StrictSequence: &proto.APLActionStrictSequence{
+		Actions: []*proto.APLAction{castDot, castDot, castDot},
+	}}
+	rsr.Raid.Parties[0].Players[0].Rotation = rotation
+
+	result := RunRaidSim(rsr)
+	if result.Error != nil {
+		t.Fatalf("Sim failed: %s", result.Error.Message)
+	}
+
+	// The entry is executed once each time the sequence starts, not once per action of the sequence.
+	player := result.RaidMetrics.Parties[0].Players[0]
+	var casts int32
+	for _, action := range player.Actions {
+		if action.Id.GetSpellId() == 42 {
+			casts += action.Targets[0].Casts
+		}
+	}
+	sequences := float64(casts) / 3 / float64(rsr.SimOptions.Iterations)
+	if executions := player.AplMetrics.PriorityList[0].ExecutionsAvg; sequences == 0 || math.Abs(executions-sequences) > 1e-9 {
+		t.Errorf("Expected %0.2f sequence executions per iteration, got %0.2f", sequences, executions)
+	}
+}
@@ -0,0 +1,316 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/wowsims/sod/sim/core/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// APL rotations have a text format, which is easier to read and diff than their JSON, e.g.
+//
+//	prepull:
+//		cast_spell(spell:469145) at -5s
+//
+//	priority:
+//		# Keep the debuff up.
+//		cast_spell(spell:12654:r2) if aura_remaining_time(spell:12654) < 2s and current_mana_percent > 30%
+//		call_list("aoe") if number_targets >= 3
+//
+//	list "aoe":
+//		hide cast_spell(spell:2120)
+//
+// Actions and values are written as their proto field name, with their fields in parentheses.
+// The field with the lowest number can be passed by position and the others by name, e.g.
+// cast_spell(spell:133, target={type=Target}).
+// Values also have operators for and, or, not, comparisons and math, and constants are written as is.
+// ActionIDs are written as spell:<id>, item:<id> or other:<OtherAction>, followed by :r<rank> and :t<tag> when set.
+// Comments right above a priority list item are its notes.
+//
+// Only the prepull actions, priority list and action lists are part of the text format.
+
+// Operator precedences, from loosest to tightest.
+const (
+	aplPrecOr = iota + 1
+	aplPrecAnd
+	aplPrecNot
+	aplPrecCmp
+	aplPrecAdd
+	aplPrecMul
+)
+
+var aplCompareOperators = map[proto.APLValueCompare_ComparisonOperator]string{
+	proto.APLValueCompare_OpEq: "==",
+	proto.APLValueCompare_OpNe: "!=",
+	proto.APLValueCompare_OpLt: "<",
+	proto.APLValueCompare_OpLe: "<=",
+	proto.APLValueCompare_OpGt: ">",
+	proto.APLValueCompare_OpGe: ">=",
+}
+
+var aplMathOperators = map[proto.APLValueMath_MathOperator]string{
+	proto.APLValueMath_OpAdd: "+",
+	proto.APLValueMath_OpSub: "-",
+	proto.APLValueMath_OpMul: "*",
+	proto.APLValueMath_OpDiv: "/",
+}
+
+// Constants which can be written without quotes, such as 3, 2.5s or 30%.
+var aplConstLiteral = regexp.MustCompile(`^-?[0-9.][0-9A-Za-z.%]*$`)
+
+// FormatAPLRotation returns the text format of an APL rotation.
+func FormatAPLRotation(rotation *proto.APLRotation) string {
+	var sections []string
+
+	if len(rotation.GetPrepullActions()) > 0 {
+		section := "prepull:\n"
+		for _, item := range rotation.GetPrepullActions() {
+			line := formatAPLAction(item.Action)
+			if item.DoAtValue != nil {
+				line += " at " + formatAPLValue(item.DoAtValue, 0)
+			}
+			section += formatAPLItemLine(line, item.Hide, "")
+		}
+		sections = append(sections, section)
+	}
+
+	if len(rotation.GetPriorityList()) > 0 {
+		section := "priority:\n"
+		for _, item := range rotation.GetPriorityList() {
+			section += formatAPLItemLine(formatAPLAction(item.Action), item.Hide, item.Notes)
+		}
+		sections = append(sections, section)
+	}
+
+	for _, list := range rotation.GetActionLists() {
+		section := fmt.Sprintf("list %s:\n", strconv.Quote(list.Name))
+		for _, item := range list.Items {
+			section += formatAPLItemLine(formatAPLAction(item.Action), item.Hide, item.Notes)
+		}
+		sections = append(sections, section)
+	}
+
+	return strings.Join(sections, "\n")
+}
+
+func formatAPLItemLine(line string, hide bool, notes string) string {
+	var text string
+	if notes != "" {
+		for _, note := range strings.Split(notes, "\n") {
+			if note == "" {
+				text += "\t#\n"
+			} else {
+				text += "\t# " + note + "\n"
+			}
+		}
+	}
+	if hide {
+		line = "hide " + line
+	}
+	return text + "\t" + line + "\n"
+}
+
+func formatAPLAction(action *proto.APLAction) string {
+	if action == nil {
+		return "{}"
+	}
+
+	var text string
+	msg := action.ProtoReflect()
+	if field := msg.WhichOneof(msg.Descriptor().Oneofs().ByName("action")); field != nil {
+		text = formatAPLCall(string(field.Name()), msg.Get(field).Message())
+	} else {
+		text = "{}"
+	}
+
+	if action.Condition != nil {
+		text += " if " + formatAPLValue(action.Condition, 0)
+	}
+	return text
+}
+
+// Formats a value, with parentheses if its operator binds looser than minPrec.
+func formatAPLValue(value *proto.APLValue, minPrec int) string {
+	text, prec := formatAPLValueOperator(value)
+	if prec == 0 {
+		msg := value.ProtoReflect()
+		if field := msg.WhichOneof(msg.Descriptor().Oneofs().ByName("value")); field != nil {
+			return formatAPLCall(string(field.Name()), msg.Get(field).Message())
+		}
+		return "{}"
+	}
+	if prec < minPrec {
+		return "(" + text + ")"
+	}
+	return text
+}
+
+// Formats values which have an operator or literal, returning a precedence of 0 for the others.
+func formatAPLValueOperator(value *proto.APLValue) (string, int) {
+	switch impl := value.Value.(type) {
+	case *proto.APLValue_Const:
+		if aplConstLiteral.MatchString(impl.Const.Val) {
+			return impl.Const.Val, aplPrecMul + 1
+		}
+		return strconv.Quote(impl.Const.Val), aplPrecMul + 1
+	case *proto.APLValue_And:
+		if len(impl.And.Vals) >= 2 {
+			return formatAPLValues(impl.And.Vals, " and ", aplPrecAnd+1), aplPrecAnd
+		}
+	case *proto.APLValue_Or:
+		if len(impl.Or.Vals) >= 2 {
+			return formatAPLValues(impl.Or.Vals, " or ", aplPrecOr+1), aplPrecOr
+		}
+	case *proto.APLValue_Not:
+		if impl.Not.Val != nil {
+			return "not " + formatAPLValue(impl.Not.Val, aplPrecNot), aplPrecNot
+		}
+	case *proto.APLValue_Cmp:
+		if op, ok := aplCompareOperators[impl.Cmp.Op]; ok && impl.Cmp.Lhs != nil && impl.Cmp.Rhs != nil {
+			return formatAPLValue(impl.Cmp.Lhs, aplPrecCmp+1) + " " + op + " " + formatAPLValue(impl.Cmp.Rhs, aplPrecCmp+1), aplPrecCmp
+		}
+	case *proto.APLValue_Math:
+		if op, ok := aplMathOperators[impl.Math.Op]; ok && impl.Math.Lhs != nil && impl.Math.Rhs != nil {
+			prec := aplPrecAdd
+			if impl.Math.Op == proto.APLValueMath_OpMul || impl.Math.Op == proto.APLValueMath_OpDiv {
+				prec = aplPrecMul
+			}
+			return formatAPLValue(impl.Math.Lhs, prec) + " " + op + " " + formatAPLValue(impl.Math.Rhs, prec+1), prec
+		}
+	}
+	return "", 0
+}
+
+func formatAPLValues(values []*proto.APLValue, separator string, minPrec int) string {
+	return strings.Join(MapSlice(values, func(value *proto.APLValue) string { return formatAPLValue(value, minPrec) }), separator)
+}
+
+// Returns the field of an action or value which can be passed by position, or nil if it has no fields.
+func aplPositionalField(msg protoreflect.MessageDescriptor) protoreflect.FieldDescriptor {
+	var positional protoreflect.FieldDescriptor
+	fields := msg.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if field := fields.Get(i); positional == nil || field.Number() < positional.Number() {
+			positional = field
+		}
+	}
+	return positional
+}
+
+// Formats an action or value as name(positional, field=value, ...). Messages with a single
+// repeated field pass all of its elements by position instead, e.g. max(a, b).
+func formatAPLCall(name string, msg protoreflect.Message) string {
+	fields := msg.Descriptor().Fields()
+	positional := aplPositionalField(msg.Descriptor())
+
+	var args []string
+	if positional != nil && msg.Has(positional) {
+		if positional.IsList() && fields.Len() == 1 {
+			list := msg.Get(positional).List()
+			for i := 0; i < list.Len(); i++ {
+				args = append(args, formatAPLFieldValue(positional, list.Get(i)))
+			}
+		} else {
+			args = append(args, formatAPLField(positional, msg.Get(positional)))
+		}
+	}
+	for i := 0; i < fields.Len(); i++ {
+		if field := fields.Get(i); field != positional && msg.Has(field) {
+			args = append(args, string(field.Name())+"="+formatAPLField(field, msg.Get(field)))
+		}
+	}
+
+	if len(args) == 0 && !aplTextKeywords[name] {
+		return name
+	}
+	return name + "(" + strings.Join(args, ", ") + ")"
+}
+
+func formatAPLField(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	if !field.IsList() {
+		return formatAPLFieldValue(field, value)
+	}
+	list := value.List()
+	elems := make([]string, list.Len())
+	for i := range elems {
+		elems[i] = formatAPLFieldValue(field, list.Get(i))
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+func formatAPLFieldValue(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return strconv.FormatBool(value.Bool())
+	case protoreflect.EnumKind:
+		if enumValue := field.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name())
+		}
+		return strconv.Itoa(int(value.Enum()))
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return strconv.FormatInt(value.Int(), 10)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return strconv.FormatUint(value.Uint(), 10)
+	case protoreflect.FloatKind:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 32)
+	case protoreflect.DoubleKind:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+	case protoreflect.StringKind:
+		return strconv.Quote(value.String())
+	case protoreflect.MessageKind:
+		return formatAPLMessage(value.Message())
+	default:
+		return strconv.Quote(string(value.Bytes()))
+	}
+}
+
+func formatAPLMessage(msg protoreflect.Message) string {
+	switch impl := msg.Interface().(type) {
+	case *proto.APLValue:
+		return formatAPLValue(impl, 0)
+	case *proto.APLAction:
+		return formatAPLAction(impl)
+	case *proto.ActionID:
+		if text, ok := formatAPLActionID(impl); ok {
+			return text
+		}
+	}
+
+	var args []string
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if msg.Has(field) {
+			args = append(args, string(field.Name())+"="+formatAPLField(field, msg.Get(field)))
+		}
+	}
+	return "{" + strings.Join(args, ", ") + "}"
+}
+
+func formatAPLActionID(id *proto.ActionID) (string, bool) {
+	var text string
+	switch rawID := id.RawId.(type) {
+	case *proto.ActionID_SpellId:
+		text = fmt.Sprintf("spell:%d", rawID.SpellId)
+	case *proto.ActionID_ItemId:
+		text = fmt.Sprintf("item:%d", rawID.ItemId)
+	case *proto.ActionID_OtherId:
+		text = "other:" + rawID.OtherId.String()
+	default:
+		return "", false
+	}
+	if id.Rank < 0 || id.Tag < 0 {
+		return "", false
+	}
+	if id.Rank != 0 {
+		text += fmt.Sprintf(":r%d", id.Rank)
+	}
+	if id.Tag != 0 {
+		text += fmt.Sprintf(":t%d", id.Tag)
+	}
+	return text, true
+}
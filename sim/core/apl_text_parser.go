@@ -0,0 +1,723 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wowsims/sod/sim/core/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Words of the APL text format which aren't action or value names.
+var aplTextKeywords = map[string]bool{
+	"and":  true,
+	"or":   true,
+	"not":  true,
+	"if":   true,
+	"at":   true,
+	"hide": true,
+}
+
+type aplTokenKind int
+
+const (
+	aplTokenEOF aplTokenKind = iota
+	aplTokenNewline
+	aplTokenComment
+	aplTokenIdent
+	aplTokenNumber
+	aplTokenString
+	aplTokenPunct
+)
+
+type aplToken struct {
+	kind aplTokenKind
+	text string
+	line int
+	col  int
+}
+
+func (token aplToken) String() string {
+	switch token.kind {
+	case aplTokenEOF:
+		return "end of input"
+	case aplTokenNewline:
+		return "end of line"
+	default:
+		return strconv.Quote(token.text)
+	}
+}
+
+// Whether a '-' right after this token is a binary minus, rather than the sign of a number.
+func (token aplToken) endsOperand() bool {
+	switch token.kind {
+	case aplTokenNumber, aplTokenString:
+		return true
+	case aplTokenIdent:
+		return !aplTextKeywords[token.text]
+	case aplTokenPunct:
+		return token.text == ")" || token.text == "]" || token.text == "}"
+	}
+	return false
+}
+
+func isAPLIdentChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+func isAPLNumberChar(c byte) bool {
+	return c == '.' || c == '%' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isAPLDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Splits text into tokens. Newlines and comments are only tokens outside of brackets, so
+// long actions can be split across lines.
+func tokenizeAPLText(text string) ([]aplToken, error) {
+	var tokens []aplToken
+	depth := 0
+	line, lineStart := 1, 0
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		start := i
+		token := aplToken{line: line, col: i - lineStart + 1}
+
+		switch {
+		case c == '\n':
+			i++
+			if depth == 0 {
+				token.kind = aplTokenNewline
+				tokens = append(tokens, token)
+			}
+			line, lineStart = line+1, i
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case c == '#':
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+			if depth == 0 {
+				token.kind = aplTokenComment
+				token.text = strings.TrimSuffix(text[start+1:i], "\r")
+				tokens = append(tokens, token)
+			}
+			continue
+		case isAPLIdentChar(c, true):
+			for i < len(text) && isAPLIdentChar(text[i], false) {
+				i++
+			}
+			token.kind = aplTokenIdent
+		case isAPLDigit(c) || c == '.' ||
+			(c == '-' && i+1 < len(text) && (isAPLDigit(text[i+1]) || text[i+1] == '.') && (len(tokens) == 0 || !tokens[len(tokens)-1].endsOperand())):
+			for i++; i < len(text) && isAPLNumberChar(text[i]); i++ {
+			}
+			token.kind = aplTokenNumber
+		case c == '"':
+			for i++; i < len(text) && text[i] != '"' && text[i] != '\n'; i++ {
+				if text[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(text) || text[i] != '"' {
+				return nil, fmt.Errorf("line %d, column %d: unterminated string", token.line, token.col)
+			}
+			i++
+			unquoted, err := strconv.Unquote(text[start:i])
+			if err != nil {
+				return nil, fmt.Errorf("line %d, column %d: invalid string %s", token.line, token.col, text[start:i])
+			}
+			token.kind = aplTokenString
+			token.text = unquoted
+			tokens = append(tokens, token)
+			continue
+		case strings.HasPrefix(text[i:], "==") || strings.HasPrefix(text[i:], "!=") ||
+			strings.HasPrefix(text[i:], "<=") || strings.HasPrefix(text[i:], ">="):
+			i += 2
+			token.kind = aplTokenPunct
+		case strings.IndexByte("()[]{},:=<>+-*/", c) >= 0:
+			i++
+			token.kind = aplTokenPunct
+			if strings.IndexByte("([{", c) >= 0 {
+				depth++
+			} else if strings.IndexByte(")]}", c) >= 0 && depth > 0 {
+				depth--
+			}
+		default:
+			return nil, fmt.Errorf("line %d, column %d: unexpected character %q", token.line, token.col, c)
+		}
+
+		token.text = text[start:i]
+		tokens = append(tokens, token)
+	}
+
+	tokens = append(tokens, aplToken{kind: aplTokenEOF, line: line, col: len(text) - lineStart + 1})
+	return tokens, nil
+}
+
+type aplTextParser struct {
+	tokens []aplToken
+	pos    int
+}
+
+func (parser *aplTextParser) peek() aplToken {
+	return parser.tokens[parser.pos]
+}
+
+func (parser *aplTextParser) peekAt(offset int) aplToken {
+	return parser.tokens[min(parser.pos+offset, len(parser.tokens)-1)]
+}
+
+func (parser *aplTextParser) next() aplToken {
+	token := parser.tokens[parser.pos]
+	if token.kind != aplTokenEOF {
+		parser.pos++
+	}
+	return token
+}
+
+func (parser *aplTextParser) isPunct(text string) bool {
+	token := parser.peek()
+	return token.kind == aplTokenPunct && token.text == text
+}
+
+func (parser *aplTextParser) isIdent(text string) bool {
+	token := parser.peek()
+	return token.kind == aplTokenIdent && token.text == text
+}
+
+func (parser *aplTextParser) errorf(token aplToken, message string, vals ...interface{}) error {
+	return fmt.Errorf("line %d, column %d: %s", token.line, token.col, fmt.Sprintf(message, vals...))
+}
+
+func (parser *aplTextParser) expectPunct(text string) error {
+	if !parser.isPunct(text) {
+		return parser.errorf(parser.peek(), "expected %q, got %s", text, parser.peek())
+	}
+	parser.next()
+	return nil
+}
+
+// ParseAPLRotation parses the text format of an APL rotation, as returned by FormatAPLRotation.
+func ParseAPLRotation(text string) (*proto.APLRotation, error) {
+	tokens, err := tokenizeAPLText(text)
+	if err != nil {
+		return nil, err
+	}
+	parser := &aplTextParser{tokens: tokens}
+
+	rotation := &proto.APLRotation{
+		Type: proto.APLRotation_TypeAPL,
+	}
+	var items *[]*proto.APLListItem
+	inPrepull := false
+	var notes []string
+
+	for {
+		token := parser.peek()
+		switch {
+		case token.kind == aplTokenEOF:
+			return rotation, nil
+		case token.kind == aplTokenNewline:
+			// Comments separated from an item by a blank line aren't its notes.
+			if parser.pos > 0 && parser.tokens[parser.pos-1].kind == aplTokenNewline {
+				notes = nil
+			}
+			parser.next()
+			continue
+		case token.kind == aplTokenComment:
+			parser.next()
+			notes = append(notes, strings.TrimPrefix(token.text, " "))
+			continue
+		case (parser.isIdent("prepull") || parser.isIdent("priority")) && parser.peekAt(1).text == ":":
+			parser.next()
+			parser.next()
+			inPrepull = token.text == "prepull"
+			if inPrepull {
+				items = nil
+			} else {
+				items = &rotation.PriorityList
+			}
+		case parser.isIdent("list") && parser.peekAt(1).kind == aplTokenString && parser.peekAt(2).text == ":":
+			parser.next()
+			list := &proto.APLActionList{Name: parser.next().text}
+			parser.next()
+			rotation.ActionLists = append(rotation.ActionLists, list)
+			inPrepull, items = false, &list.Items
+		case inPrepull:
+			item, err := parser.parsePrepullItem()
+			if err != nil {
+				return nil, err
+			}
+			rotation.PrepullActions = append(rotation.PrepullActions, item)
+		case items != nil:
+			item, err := parser.parseListItem()
+			if err != nil {
+				return nil, err
+			}
+			item.Notes = strings.Join(notes, "\n")
+			*items = append(*items, item)
+		default:
+			return nil, parser.errorf(token, "expected 'prepull:', 'priority:' or 'list \"<name>\":', got %s", token)
+		}
+
+		notes = nil
+		if token := parser.peek(); token.kind != aplTokenNewline && token.kind != aplTokenEOF {
+			return nil, parser.errorf(token, "expected end of line, got %s", token)
+		}
+	}
+}
+
+func (parser *aplTextParser) parseHide() bool {
+	if parser.isIdent("hide") {
+		if next := parser.peekAt(1); next.kind == aplTokenIdent || next.text == "{" {
+			parser.next()
+			return true
+		}
+	}
+	return false
+}
+
+func (parser *aplTextParser) parsePrepullItem() (*proto.APLPrepullAction, error) {
+	item := &proto.APLPrepullAction{Hide: parser.parseHide()}
+	action, err := parser.parseAction()
+	if err != nil {
+		return nil, err
+	}
+	item.Action = action
+
+	if parser.isIdent("at") {
+		parser.next()
+		if item.DoAtValue, err = parser.parseValue(0); err != nil {
+			return nil, err
+		}
+	}
+	return item, nil
+}
+
+func (parser *aplTextParser) parseListItem() (*proto.APLListItem, error) {
+	item := &proto.APLListItem{Hide: parser.parseHide()}
+	action, err := parser.parseAction()
+	if err != nil {
+		return nil, err
+	}
+	item.Action = action
+	return item, nil
+}
+
+// Parses an action, followed by its condition if any.
+func (parser *aplTextParser) parseAction() (*proto.APLAction, error) {
+	action := &proto.APLAction{}
+	if err := parser.parseOneof(action.ProtoReflect(), "action"); err != nil {
+		return nil, err
+	}
+
+	if parser.isIdent("if") {
+		parser.next()
+		condition, err := parser.parseValue(0)
+		if err != nil {
+			return nil, err
+		}
+		action.Condition = condition
+	}
+	return action, nil
+}
+
+// Parses a value, stopping at operators which bind looser than minPrec.
+func (parser *aplTextParser) parseValue(minPrec int) (*proto.APLValue, error) {
+	var lhs *proto.APLValue
+	var err error
+	if parser.isIdent("not") && parser.peekAt(1).text != "(" {
+		parser.next()
+		val, err := parser.parseValue(aplPrecNot)
+		if err != nil {
+			return nil, err
+		}
+		lhs = &proto.APLValue{Value: &proto.APLValue_Not{Not: &proto.APLValueNot{Val: val}}}
+	} else if lhs, err = parser.parsePrimaryValue(); err != nil {
+		return nil, err
+	}
+
+	for {
+		token := parser.peek()
+		prec := parser.binaryPrec(token)
+		if prec == 0 || prec < minPrec {
+			return lhs, nil
+		}
+
+		if prec == aplPrecAnd || prec == aplPrecOr {
+			vals := []*proto.APLValue{lhs}
+			for parser.peek().kind == token.kind && parser.peek().text == token.text {
+				parser.next()
+				val, err := parser.parseValue(prec + 1)
+				if err != nil {
+					return nil, err
+				}
+				vals = append(vals, val)
+			}
+			if prec == aplPrecAnd {
+				lhs = &proto.APLValue{Value: &proto.APLValue_And{And: &proto.APLValueAnd{Vals: vals}}}
+			} else {
+				lhs = &proto.APLValue{Value: &proto.APLValue_Or{Or: &proto.APLValueOr{Vals: vals}}}
+			}
+			continue
+		}
+
+		parser.next()
+		rhs, err := parser.parseValue(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		if prec == aplPrecCmp {
+			lhs = &proto.APLValue{Value: &proto.APLValue_Cmp{Cmp: &proto.APLValueCompare{Op: aplCompareOperatorByText(token.text), Lhs: lhs, Rhs: rhs}}}
+		} else {
+			lhs = &proto.APLValue{Value: &proto.APLValue_Math{Math: &proto.APLValueMath{Op: aplMathOperatorByText(token.text), Lhs: lhs, Rhs: rhs}}}
+		}
+	}
+}
+
+// Returns the precedence of a binary operator, or 0 if the token isn't one.
+func (parser *aplTextParser) binaryPrec(token aplToken) int {
+	switch {
+	case token.kind == aplTokenIdent && token.text == "and":
+		return aplPrecAnd
+	case token.kind == aplTokenIdent && token.text == "or":
+		return aplPrecOr
+	case token.kind != aplTokenPunct:
+		return 0
+	case aplCompareOperatorByText(token.text) != proto.APLValueCompare_OpUnknown:
+		return aplPrecCmp
+	case token.text == "+" || token.text == "-":
+		return aplPrecAdd
+	case token.text == "*" || token.text == "/":
+		return aplPrecMul
+	}
+	return 0
+}
+
+func aplCompareOperatorByText(text string) proto.APLValueCompare_ComparisonOperator {
+	for op, opText := range aplCompareOperators {
+		if opText == text {
+			return op
+		}
+	}
+	return proto.APLValueCompare_OpUnknown
+}
+
+func aplMathOperatorByText(text string) proto.APLValueMath_MathOperator {
+	for op, opText := range aplMathOperators {
+		if opText == text {
+			return op
+		}
+	}
+	return proto.APLValueMath_OpUnknown
+}
+
+func (parser *aplTextParser) parsePrimaryValue() (*proto.APLValue, error) {
+	token := parser.peek()
+	switch {
+	case parser.isPunct("("):
+		parser.next()
+		value, err := parser.parseValue(0)
+		if err != nil {
+			return nil, err
+		}
+		return value, parser.expectPunct(")")
+	case token.kind == aplTokenNumber || token.kind == aplTokenString:
+		parser.next()
+		return &proto.APLValue{Value: &proto.APLValue_Const{Const: &proto.APLValueConst{Val: token.text}}}, nil
+	}
+
+	value := &proto.APLValue{}
+	if err := parser.parseOneof(value.ProtoReflect(), "value"); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Parses the name and arguments of an action or value into the matching field of a oneof,
+// or a message literal for the whole message.
+func (parser *aplTextParser) parseOneof(msg protoreflect.Message, oneofName protoreflect.Name) error {
+	if parser.isPunct("{") {
+		return parser.parseMessageLiteral(msg)
+	}
+
+	token := parser.next()
+	if token.kind != aplTokenIdent {
+		return parser.errorf(token, "expected %s, got %s", oneofName, token)
+	}
+	field := msg.Descriptor().Oneofs().ByName(oneofName).Fields().ByName(protoreflect.Name(token.text))
+	if field == nil {
+		return parser.errorf(token, "unknown %s %q", oneofName, token.text)
+	}
+
+	impl := msg.NewField(field).Message()
+	if err := parser.parseCallArgs(impl); err != nil {
+		return err
+	}
+	msg.Set(field, protoreflect.ValueOfMessage(impl))
+	return nil
+}
+
+// Parses optional arguments in parentheses, as formatted by formatAPLCall.
+func (parser *aplTextParser) parseCallArgs(msg protoreflect.Message) error {
+	if !parser.isPunct("(") {
+		return nil
+	}
+	parser.next()
+
+	fields := msg.Descriptor().Fields()
+	positional := aplPositionalField(msg.Descriptor())
+	spread := fields.Len() == 1 && positional.IsList()
+	for i := 0; !parser.isPunct(")"); i++ {
+		if i > 0 {
+			if err := parser.expectPunct(","); err != nil {
+				return err
+			}
+			if parser.isPunct(")") {
+				break
+			}
+		}
+
+		token := parser.peek()
+		if token.kind == aplTokenIdent && parser.peekAt(1).text == "=" {
+			if err := parser.parseNamedField(msg); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if positional == nil || (i > 0 && !spread) {
+			return parser.errorf(token, "unexpected positional argument %s", token)
+		}
+		if spread {
+			list := msg.Mutable(positional).List()
+			elem, err := parser.parseFieldValue(positional, list.NewElement)
+			if err != nil {
+				return err
+			}
+			list.Append(elem)
+		} else if err := parser.parseField(msg, positional); err != nil {
+			return err
+		}
+	}
+	parser.next()
+	return nil
+}
+
+// Parses {field=value, ...} into msg.
+func (parser *aplTextParser) parseMessageLiteral(msg protoreflect.Message) error {
+	if err := parser.expectPunct("{"); err != nil {
+		return err
+	}
+	for i := 0; !parser.isPunct("}"); i++ {
+		if i > 0 {
+			if err := parser.expectPunct(","); err != nil {
+				return err
+			}
+			if parser.isPunct("}") {
+				break
+			}
+		}
+		if err := parser.parseNamedField(msg); err != nil {
+			return err
+		}
+	}
+	parser.next()
+	return nil
+}
+
+func (parser *aplTextParser) parseNamedField(msg protoreflect.Message) error {
+	token := parser.next()
+	if token.kind != aplTokenIdent {
+		return parser.errorf(token, "expected field name, got %s", token)
+	}
+	field := msg.Descriptor().Fields().ByName(protoreflect.Name(token.text))
+	if field == nil {
+		return parser.errorf(token, "unknown field %q of %s", token.text, msg.Descriptor().Name())
+	}
+	if err := parser.expectPunct("="); err != nil {
+		return err
+	}
+	return parser.parseField(msg, field)
+}
+
+func (parser *aplTextParser) parseField(msg protoreflect.Message, field protoreflect.FieldDescriptor) error {
+	if !field.IsList() {
+		value, err := parser.parseFieldValue(field, func() protoreflect.Value { return msg.NewField(field) })
+		if err != nil {
+			return err
+		}
+		msg.Set(field, value)
+		return nil
+	}
+
+	if err := parser.expectPunct("["); err != nil {
+		return err
+	}
+	list := msg.Mutable(field).List()
+	for i := 0; !parser.isPunct("]"); i++ {
+		if i > 0 {
+			if err := parser.expectPunct(","); err != nil {
+				return err
+			}
+			if parser.isPunct("]") {
+				break
+			}
+		}
+		elem, err := parser.parseFieldValue(field, list.NewElement)
+		if err != nil {
+			return err
+		}
+		list.Append(elem)
+	}
+	parser.next()
+	return nil
+}
+
+// Parses a single value of a field, using newValue to create messages.
+func (parser *aplTextParser) parseFieldValue(field protoreflect.FieldDescriptor, newValue func() protoreflect.Value) (protoreflect.Value, error) {
+	if field.Kind() == protoreflect.MessageKind {
+		msg := newValue().Message()
+		var err error
+		switch impl := msg.Interface().(type) {
+		case *proto.APLValue:
+			var value *proto.APLValue
+			if value, err = parser.parseValue(0); err == nil {
+				msg = value.ProtoReflect()
+			}
+		case *proto.APLAction:
+			var action *proto.APLAction
+			if action, err = parser.parseAction(); err == nil {
+				msg = action.ProtoReflect()
+			}
+		case *proto.ActionID:
+			if parser.peek().kind == aplTokenIdent && parser.peekAt(1).text == ":" {
+				err = parser.parseActionID(impl)
+			} else {
+				err = parser.parseMessageLiteral(msg)
+			}
+		default:
+			err = parser.parseMessageLiteral(msg)
+		}
+		return protoreflect.ValueOfMessage(msg), err
+	}
+
+	token := parser.next()
+	invalid := func() (protoreflect.Value, error) {
+		return protoreflect.Value{}, parser.errorf(token, "invalid value %s for field %s", token, field.Name())
+	}
+
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		if token.kind != aplTokenIdent || (token.text != "true" && token.text != "false") {
+			return invalid()
+		}
+		return protoreflect.ValueOfBool(token.text == "true"), nil
+	case protoreflect.EnumKind:
+		if token.kind == aplTokenIdent {
+			if enumValue := field.Enum().Values().ByName(protoreflect.Name(token.text)); enumValue != nil {
+				return protoreflect.ValueOfEnum(enumValue.Number()), nil
+			}
+		} else if number, err := strconv.ParseInt(token.text, 10, 32); token.kind == aplTokenNumber && err == nil {
+			return protoreflect.ValueOfEnum(protoreflect.EnumNumber(number)), nil
+		}
+		return invalid()
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if number, err := strconv.ParseInt(token.text, 10, 32); token.kind == aplTokenNumber && err == nil {
+			return protoreflect.ValueOfInt32(int32(number)), nil
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if number, err := strconv.ParseInt(token.text, 10, 64); token.kind == aplTokenNumber && err == nil {
+			return protoreflect.ValueOfInt64(number), nil
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if number, err := strconv.ParseUint(token.text, 10, 32); token.kind == aplTokenNumber && err == nil {
+			return protoreflect.ValueOfUint32(uint32(number)), nil
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if number, err := strconv.ParseUint(token.text, 10, 64); token.kind == aplTokenNumber && err == nil {
+			return protoreflect.ValueOfUint64(number), nil
+		}
+	case protoreflect.FloatKind:
+		if number, err := strconv.ParseFloat(token.text, 32); token.kind == aplTokenNumber && err == nil {
+			return protoreflect.ValueOfFloat32(float32(number)), nil
+		}
+	case protoreflect.DoubleKind:
+		if number, err := strconv.ParseFloat(token.text, 64); token.kind == aplTokenNumber && err == nil {
+			return protoreflect.ValueOfFloat64(number), nil
+		}
+	case protoreflect.StringKind:
+		if token.kind == aplTokenString {
+			return protoreflect.ValueOfString(token.text), nil
+		}
+	case protoreflect.BytesKind:
+		if token.kind == aplTokenString {
+			return protoreflect.ValueOfBytes([]byte(token.text)), nil
+		}
+	}
+	return invalid()
+}
+
+// Parses spell:<id>, item:<id> or other:<OtherAction>, followed by :r<rank> and :t<tag>.
+func (parser *aplTextParser) parseActionID(id *proto.ActionID) error {
+	kind := parser.next()
+	parser.next()
+	token := parser.next()
+
+	switch kind.text {
+	case "spell", "item":
+		number, err := strconv.ParseInt(token.text, 10, 32)
+		if token.kind != aplTokenNumber || err != nil {
+			return parser.errorf(token, "invalid %s id %s", kind.text, token)
+		}
+		if kind.text == "spell" {
+			id.RawId = &proto.ActionID_SpellId{SpellId: int32(number)}
+		} else {
+			id.RawId = &proto.ActionID_ItemId{ItemId: int32(number)}
+		}
+	case "other":
+		otherID, ok := proto.OtherAction_value[token.text]
+		if number, err := strconv.ParseInt(token.text, 10, 32); token.kind == aplTokenNumber && err == nil {
+			otherID, ok = int32(number), true
+		}
+		if !ok {
+			return parser.errorf(token, "unknown other action %s", token)
+		}
+		id.RawId = &proto.ActionID_OtherId{OtherId: proto.OtherAction(otherID)}
+	default:
+		return parser.errorf(kind, "expected spell, item or other, got %s", kind)
+	}
+
+	var hasRank, hasTag bool
+	for parser.isPunct(":") {
+		parser.next()
+		token := parser.next()
+		if token.kind != aplTokenIdent || len(token.text) < 2 {
+			return parser.errorf(token, "expected :r<rank> or :t<tag>, got %s", token)
+		}
+		number, err := strconv.ParseInt(token.text[1:], 10, 32)
+		switch {
+		case err != nil:
+			return parser.errorf(token, "expected :r<rank> or :t<tag>, got %s", token)
+		case token.text[0] == 'r':
+			if hasRank {
+				return parser.errorf(token, "duplicate rank %s", token)
+			}
+			id.Rank, hasRank = int32(number), true
+		case token.text[0] == 't':
+			if hasTag {
+				return parser.errorf(token, "duplicate tag %s", token)
+			}
+			id.Tag, hasTag = int32(number), true
+		default:
+			return parser.errorf(token, "expected :r<rank> or :t<tag>, got %s", token)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wowsims/sod/sim/core/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	googleProto "google.golang.org/protobuf/proto"
+)
+
+func TestAPLTextRoundTrip(t *testing.T) {
+	paths, err := filepath.Glob("../../ui/*/apls/*.apl.json")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("Failed to find APL files: %v", err)
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %s", path, err)
+		}
+		rotation := &proto.APLRotation{}
+		if err := protojson.Unmarshal(data, rotation); err != nil {
+			t.Fatalf("Failed to parse %s: %s", path, err)
+		}
+		rotation.Type = proto.APLRotation_TypeAPL
+		rotation.Simple = nil
+
+		text := FormatAPLRotation(rotation)
+		parsed, err := ParseAPLRotation(text)
+		if err != nil {
+			t.Errorf("Failed to parse the text of %s: %s\n%s", path, err, text)
+			continue
+		}
+		if !googleProto.Equal(rotation, parsed) {
+			t.Errorf("Round trip changed %s:\n%s\n%s", path, text, FormatAPLRotation(parsed))
+		}
+	}
+}
+
+func TestAPLTextParse(t *testing.T) {
+	text := `
+# Not the notes of the first item, as it's followed by a blank line.
+
+prepull:
+	cast_spell(spell:469145) at -5s
+
+priority:
+	# Keep Ignite rolling,
+	# but only with enough mana.
+	cast_spell(spell:133:r2) if aura_remaining_time(spell:12654) < 2s and current_mana_percent > 30%
+	hide run_list("aoe") if not (number_targets >= 3 or current_time - 1s * 2 > -1s)
+	sequence("opener", actions=[
+		cast_spell(spell:1),
+		wait(1s),
+	])
+
+list "aoe":
+	cast_spell(spell:2120, target={type=CurrentTarget})
+`
+	rotation, err := ParseAPLRotation(text)
+	if err != nil {
+		t.Fatalf("Failed to parse: %s", err)
+	}
+
+	const_ := func(val string) *proto.APLValue {
+		return &proto.APLValue{Value: &proto.APLValue_Const{Const: &proto.APLValueConst{Val: val}}}
+	}
+	spell := func(id int32) *proto.ActionID {
+		return &proto.ActionID{RawId: &proto.ActionID_SpellId{SpellId: id}}
+	}
+	expected := &proto.APLRotation{
+		Type: proto.APLRotation_TypeAPL,
+		PrepullActions: []*proto.APLPrepullAction{
+			{
+				Action:    &proto.APLAction{Action: &proto.APLAction_CastSpell{CastSpell: &proto.APLActionCastSpell{SpellId: spell(469145)}}},
+				DoAtValue: const_("-5s"),
+			},
+		},
+		PriorityList: []*proto.APLListItem{
+			{
+				Notes: "Keep Ignite rolling,\nbut only with enough mana.",
+				Action: &proto.APLAction{
+					Condition: &proto.APLValue{Value: &proto.APLValue_And{And: &proto.APLValueAnd{Vals: []*proto.APLValue{
+						{Value: &proto.APLValue_Cmp{Cmp: &proto.APLValueCompare{
+							Op:  proto.APLValueCompare_OpLt,
+							Lhs: &proto.APLValue{Value: &proto.APLValue_AuraRemainingTime{AuraRemainingTime: &proto.APLValueAuraRemainingTime{AuraId: spell(12654)}}},
+							Rhs: const_("2s"),
+						}}},
+						{Value: &proto.APLValue_Cmp{Cmp: &proto.APLValueCompare{
+							Op:  proto.APLValueCompare_OpGt,
+							Lhs: &proto.APLValue{Value: &proto.APLValue_CurrentManaPercent{CurrentManaPercent: &proto.APLValueCurrentManaPercent{}}},
+							Rhs: const_("30%"),
+						}}},
+					}}}},
+					Action: &proto.APLAction_CastSpell{CastSpell: &proto.APLActionCastSpell{SpellId: &proto.ActionID{RawId: &proto.ActionID_SpellId{SpellId: 133}, Rank: 2}}},
+				},
+			},
+			{
+				Hide: true,
+				Action: &proto.APLAction{
+					Condition: &proto.APLValue{Value: &proto.APLValue_Not{Not: &proto.APLValueNot{Val: &proto.APLValue{Value: &proto.APLValue_Or{Or: &proto.APLValueOr{Vals: []*proto.APLValue{
+						{Value: &proto.APLValue_Cmp{Cmp: &proto.APLValueCompare{
+							Op:  proto.APLValueCompare_OpGe,
+							Lhs: &proto.APLValue{Value: &proto.APLValue_NumberTargets{NumberTargets: &proto.APLValueNumberTargets{}}},
+							Rhs: const_("3"),
+						}}},
+						{Value: &proto.APLValue_Cmp{Cmp: &proto.APLValueCompare{
+							Op: proto.APLValueCompare_OpGt,
+							Lhs: &proto.APLValue{Value: &proto.APLValue_Math{Math: &proto.APLValueMath{
+								Op:  proto.APLValueMath_OpSub,
+								Lhs: &proto.APLValue{Value: &proto.APLValue_CurrentTime{CurrentTime: &proto.APLValueCurrentTime{}}},
+								Rhs: &proto.APLValue{Value: &proto.APLValue_Math{Math: &proto.APLValueMath{Op: proto.APLValueMath_OpMul, Lhs: const_("1s"), Rhs: const_("2")}}},
+							}}},
+							Rhs: const_("-1s"),
+						}}},
+					}}}}}}},
+					Action: &proto.APLAction_RunList{RunList: &proto.APLActionRunList{ListName: "aoe"}},
+				},
+			},
+			{
+				Action: &proto.APLAction{Action: &proto.APLAction_Sequence{Sequence: &proto.APLActionSequence{
+					Name: "opener",
+					Actions: []*proto.APLAction{
+						{Action: &proto.APLAction_CastSpell{CastSpell: &proto.APLActionCastSpell{SpellId: spell(1)}}},
+						{Action: &proto.APLAction_Wait{Wait: &proto.APLActionWait{Duration: const_("1s")}}},
+					},
+				}}},
+			},
+		},
+		ActionLists: []*proto.APLActionList{
+			{Name: "aoe", Items: []*proto.APLListItem{
+				{Action: &proto.APLAction{Action: &proto.APLAction_CastSpell{CastSpell: &proto.APLActionCastSpell{
+					SpellId: spell(2120),
+					Target:  &proto.UnitReference{Type: proto.UnitReference_CurrentTarget},
+				}}}},
+			}},
+		},
+	}
+	if !googleProto.Equal(rotation, expected) {
+		t.Fatalf("Unexpected rotation:\n%s", FormatAPLRotation(rotation))
+	}
+
+	reparsed, err := ParseAPLRotation(FormatAPLRotation(rotation))
+	if err != nil || !googleProto.Equal(rotation, reparsed) {
+		t.Errorf("Round trip changed the rotation: %v\n%s", err, FormatAPLRotation(rotation))
+	}
+}
+
+func TestAPLTextParseErrors(t *testing.T) {
+	for text, expected := range map[string]string{
+		"cast_spell(spell:1)":                       "line 1, column 1: expected 'prepull:'",
+		"priority:\n\tcast_spel(spell:1)":           "line 2, column 2: unknown action \"cast_spel\"",
+		"priority:\n\tcast_spell(spell:1) if (":     "line 2, column 26: expected value, got end of input",
+		"priority:\n\twait(1s) wait(2s)":            "line 2, column 11: expected end of line",
+		"priority:\n\tcast_spell(foo=1)":            "line 2, column 13: unknown field \"foo\" of APLActionCastSpell",
+		"priority:\n\tcast_spell(spell:1:r2:t3:r4)": "line 2, column 27: duplicate rank \"r4\"",
+		"priority:\n\tcast_spell(spell:1:t2:t3)":    "line 2, column 24: duplicate tag \"t3\"",
+	} {
+		if _, err := ParseAPLRotation(text); err == nil || !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("Expected an error starting with %q for %q, got %v", expected, text, err)
+		}
+	}
+}
@@ -91,6 +91,10 @@ func (rot *APLRotation) NewAPLValue(config *proto.APLValue) APLValue {
 		return rot.newValueRemainingTimePercent(config.GetRemainingTimePercent())
 	case *proto.APLValue_IsExecutePhase:
 		return rot.newValueIsExecutePhase(config.GetIsExecutePhase())
+	case *proto.APLValue_CurrentPhase:
+		return rot.newValueCurrentPhase(config.GetCurrentPhase())
+	case *proto.APLValue_TimeToNextPhase:
+		return rot.newValueTimeToNextPhase(config.GetTimeToNextPhase())
 	case *proto.APLValue_NumberTargets:
 		return rot.newValueNumberTargets(config.GetNumberTargets())
 	case *proto.APLValue_TargetMobType:
@@ -190,6 +194,10 @@ func (rot *APLRotation) NewAPLValue(config *proto.APLValue) APLValue {
 	case *proto.APLValue_SequenceTimeToReady:
 		return rot.newValueSequenceTimeToReady(config.GetSequenceTimeToReady())
 
+	// Variable values
+	case *proto.APLValue_Variable:
+		return rot.newValueVariable(config.GetVariable())
+
 	// Properties
 	case *proto.APLValue_ChannelClipDelay:
 		return rot.newValueChannelClipDelay(config.GetChannelClipDelay())
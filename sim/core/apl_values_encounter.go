@@ -86,7 +86,7 @@ func (value *APLValueNumberTargets) Type() proto.APLValueType {
 	return proto.APLValueType_ValueTypeInt
 }
 func (value *APLValueNumberTargets) GetInt(sim *Simulation) int32 {
-	return sim.GetNumTargets()
+	return int32(len(sim.Encounter.ActiveTargetUnits))
 }
 func (value *APLValueNumberTargets) String() string {
 	return "Num Targets"
@@ -123,6 +123,43 @@ func (value *APLValueIsExecutePhase) String() string {
 	return "Is Execute Phase"
 }
 
+type APLValueCurrentPhase struct {
+	DefaultAPLValueImpl
+}
+
+func (rot *APLRotation) newValueCurrentPhase(config *proto.APLValueCurrentPhase) APLValue {
+	return &APLValueCurrentPhase{}
+}
+func (value *APLValueCurrentPhase) Type() proto.APLValueType {
+	return proto.APLValueType_ValueTypeString
+}
+func (value *APLValueCurrentPhase) GetString(sim *Simulation) string {
+	if phase := sim.CurrentEncounterPhase(); phase != nil {
+		return phase.Name
+	}
+	return ""
+}
+func (value *APLValueCurrentPhase) String() string {
+	return "Current Phase"
+}
+
+type APLValueTimeToNextPhase struct {
+	DefaultAPLValueImpl
+}
+
+func (rot *APLRotation) newValueTimeToNextPhase(config *proto.APLValueTimeToNextPhase) APLValue {
+	return &APLValueTimeToNextPhase{}
+}
+func (value *APLValueTimeToNextPhase) Type() proto.APLValueType {
+	return proto.APLValueType_ValueTypeDuration
+}
+func (value *APLValueTimeToNextPhase) GetDuration(sim *Simulation) time.Duration {
+	return sim.TimeToNextEncounterPhase()
+}
+func (value *APLValueTimeToNextPhase) String() string {
+	return "Time To Next Phase"
+}
+
 type APLValueTargetMobType struct {
 	DefaultAPLValueImpl
 	MobType              proto.MobType
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/wowsims/sod/sim/core/proto"
+	"github.com/wowsims/sod/sim/core/simsignals"
 )
 
 func TestValueConst(t *testing.T) {
@@ -42,3 +43,85 @@ func TestValueConst(t *testing.T) {
 		t.Fatalf("Unexpected coerced duration value %s", coercedDurVal.GetDuration(sim))
 	}
 }
+
+func TestValueVariable(t *testing.T) {
+	fakeDot := &proto.ActionID{RawId: &proto.ActionID_SpellId{SpellId: 42}}
+	casts := &proto.APLValue{Value: &proto.APLValue_Variable{Variable: &proto.APLValueVariable{Name: "casts"}}}
+	rsr := fakeSimRequest()
+	rsr.Raid.Parties[0].Players[0].Rotation = &proto.APLRotation{
+		Type: proto.APLRotation_TypeAPL,
+		PriorityList: []*proto.APLListItem{
+			{Action: &proto.APLAction{
+				Condition: &proto.APLValue{Value: &proto.APLValue_Not{Not: &proto.APLValueNot{
+					Val: &proto.APLValue{Value: &proto.APLValue_DotIsActive{DotIsActive: &proto.APLValueDotIsActive{SpellId: fakeDot}}},
+				}}},
+				Action: &proto.APLAction_StrictSequence{StrictSequence: &proto.APLActionStrictSequence{Actions: []*proto.APLAction{
+					{Action: &proto.APLAction_CastSpell{CastSpell: &proto.APLActionCastSpell{SpellId: fakeDot}}},
+					{Action: &proto.APLAction_SetVariable{SetVariable: &proto.APLActionSetVariable{
+						Name: "casts",
+						Value: &proto.APLValue{Value: &proto.APLValue_Math{Math: &proto.APLValueMath{
+							Op:  proto.APLValueMath_OpAdd,
+							Lhs: casts,
+							Rhs: &proto.APLValue{Value: &proto.APLValue_Const{Const: &proto.APLValueConst{Val: "1"}}},
+						}}},
+					}}},
+				}}},
+			}},
+			{Action: &proto.APLAction{Action: &proto.APLAction_SetVariable{SetVariable: &proto.APLActionSetVariable{
+				Name:       "last evaluated",
+				IsDuration: true,
+				Value:      &proto.APLValue{Value: &proto.APLValue_CurrentTime{CurrentTime: &proto.APLValueCurrentTime{}}},
+			}}}},
+		},
+	}
+
+	sim := NewSim(rsr, simsignals.CreateSignals())
+	character := sim.Raid.Parties[0].Players[0].GetCharacter()
+	spell := character.GetSpell(ActionID{SpellID: 42})
+
+	// Variables are reset each iteration, so the count matches the casts of the current one.
+	for i := 0; i < 2; i++ {
+		startFakeSimUntil(sim, time.Second*30)
+		variables := character.Rotation.variables
+		if count, expected := variables["casts"].number, float64(spell.SpellMetrics[0].Casts); count != expected || count == 0 {
+			t.Errorf("Expected the casts variable to be %0.0f at %s, got %0.0f", expected, sim.CurrentTime, count)
+		}
+		if last := variables["last evaluated"].duration; last <= 0 || last > sim.CurrentTime {
+			t.Errorf("Expected the rotation to have been evaluated before %s, last at %s", sim.CurrentTime, last)
+		}
+		sim.runPendingActions()
+		sim.Cleanup()
+	}
+}
+
+func TestSetVariableInSequence(t *testing.T) {
+	fakeDot := &proto.ActionID{RawId: &proto.ActionID_SpellId{SpellId: 42}}
+	rsr := fakeSimRequest()
+	rsr.Raid.Parties[0].Players[0].Rotation = &proto.APLRotation{
+		Type: proto.APLRotation_TypeAPL,
+		PriorityList: []*proto.APLListItem{
+			{Action: &proto.APLAction{
+				Condition: &proto.APLValue{Value: &proto.APLValue_Not{Not: &proto.APLValueNot{
+					Val: &proto.APLValue{Value: &proto.APLValue_DotIsActive{DotIsActive: &proto.APLValueDotIsActive{SpellId: fakeDot}}},
+				}}},
+				Action: &proto.APLAction_StrictSequence{StrictSequence: &proto.APLActionStrictSequence{Actions: []*proto.APLAction{
+					{Action: &proto.APLAction_SetVariable{SetVariable: &proto.APLActionSetVariable{
+						Name:  "dotted",
+						Value: &proto.APLValue{Value: &proto.APLValue_Const{Const: &proto.APLValueConst{Val: "1"}}},
+					}}},
+					{Action: &proto.APLAction_CastSpell{CastSpell: &proto.APLActionCastSpell{SpellId: fakeDot}}},
+				}}},
+			}},
+		},
+	}
+
+	sim := NewSim(rsr, simsignals.CreateSignals())
+	character := sim.Raid.Parties[0].Players[0].GetCharacter()
+	spell := character.GetSpell(ActionID{SpellID: 42})
+
+	// Setting a variable to its current value must not block the sequence.
+	startFakeSimUntil(sim, time.Second*60)
+	if casts := spell.SpellMetrics[0].Casts; casts < 3 {
+		t.Errorf("Expected the dot to be refreshed every 18s, got %d casts in %s", casts, sim.CurrentTime)
+	}
+}
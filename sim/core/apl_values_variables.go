@@ -0,0 +1,118 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+// APLVariable is a number or duration stored by a rotation with APLActionSetVariable.
+type APLVariable struct {
+	Name       string
+	IsDuration bool
+
+	// Reset at the start of each iteration.
+	number   float64
+	duration time.Duration
+}
+
+func (variable *APLVariable) valueType() proto.APLValueType {
+	if variable.IsDuration {
+		return proto.APLValueType_ValueTypeDuration
+	}
+	return proto.APLValueType_ValueTypeFloat
+}
+
+func (variable *APLVariable) reset() {
+	variable.number = 0
+	variable.duration = 0
+}
+
+func (variable *APLVariable) String() string {
+	if variable.IsDuration {
+		return variable.duration.String()
+	}
+	return fmt.Sprintf("%0.3f", variable.number)
+}
+
+// Registers the variables set anywhere in the rotation, so values can read them regardless of
+// whether they come before or after the actions setting them. The first action setting a
+// variable decides its type.
+func (rot *APLRotation) registerVariables(config *proto.APLRotation) {
+	rot.variables = make(map[string]*APLVariable)
+
+	var visit func(action *proto.APLAction)
+	visit = func(action *proto.APLAction) {
+		switch impl := action.GetAction().(type) {
+		case *proto.APLAction_SetVariable:
+			name := impl.SetVariable.Name
+			if name != "" && rot.variables[name] == nil {
+				rot.variables[name] = &APLVariable{
+					Name:       name,
+					IsDuration: impl.SetVariable.IsDuration,
+				}
+			}
+		case *proto.APLAction_Schedule:
+			visit(impl.Schedule.InnerAction)
+		case *proto.APLAction_Sequence:
+			for _, subaction := range impl.Sequence.Actions {
+				visit(subaction)
+			}
+		case *proto.APLAction_StrictSequence:
+			for _, subaction := range impl.StrictSequence.Actions {
+				visit(subaction)
+			}
+		}
+	}
+
+	for _, prepullItem := range config.PrepullActions {
+		if !prepullItem.Hide {
+			visit(prepullItem.Action)
+		}
+	}
+	for _, aplItem := range config.PriorityList {
+		if !aplItem.Hide {
+			visit(aplItem.Action)
+		}
+	}
+	for _, list := range config.ActionLists {
+		for _, aplItem := range list.Items {
+			if !aplItem.Hide {
+				visit(aplItem.Action)
+			}
+		}
+	}
+}
+
+type APLValueVariable struct {
+	DefaultAPLValueImpl
+	variable *APLVariable
+}
+
+func (rot *APLRotation) newValueVariable(config *proto.APLValueVariable) APLValue {
+	if config.Name == "" {
+		rot.ValidationWarning("Variable() must provide a variable name")
+		return nil
+	}
+	variable := rot.variables[config.Name]
+	if variable == nil {
+		rot.ValidationWarning("No action sets variable: '%s'", config.Name)
+		return nil
+	}
+	return &APLValueVariable{
+		variable: variable,
+	}
+}
+func (value *APLValueVariable) Type() proto.APLValueType {
+	return value.variable.valueType()
+}
+func (value *APLValueVariable) GetFloat(sim *Simulation) float64 {
+	return value.variable.number
+}
+func (value *APLValueVariable) GetDuration(sim *Simulation) time.Duration {
+	return value.variable.duration
+}
+func (value *APLValueVariable) String() string {
+	return fmt.Sprintf("Variable(%s)", value.variable.Name)
+}
@@ -185,7 +185,7 @@ func (aura *Aura) Refresh(sim *Simulation) {
 	}
 
 	if sim.Log != nil && aura.IsActive() && !aura.ActionID.IsEmptyAction() {
-		aura.Unit.Log(sim, "Aura refreshed: %s", aura.ActionID)
+		aura.logEvent(sim, proto.AuraEventType_AuraRefreshed, 0, 0)
 	}
 
 	if aura.OnRefresh != nil {
@@ -218,7 +218,7 @@ func (aura *Aura) SetStacks(sim *Simulation, newStacks int32) {
 	}
 
 	if sim.Log != nil {
-		aura.Unit.Log(sim, "%s stacks: %d --> %d", aura.ActionID, oldStacks, newStacks)
+		aura.logEvent(sim, proto.AuraEventType_AuraStacksChanged, oldStacks, newStacks)
 	}
 	aura.stacks = newStacks
 	if aura.OnStacksChange != nil {
@@ -888,7 +888,7 @@ func (aura *Aura) Activate(sim *Simulation) {
 	}
 
 	if sim.Log != nil && !aura.ActionID.IsEmptyAction() {
-		aura.Unit.Log(sim, "Aura gained: %s", aura.ActionID)
+		aura.logEvent(sim, proto.AuraEventType_AuraGained, 0, 0)
 	}
 
 	// don't invoke possible callbacks until the internal state is consistent
@@ -909,8 +909,11 @@ func (aura *Aura) Deactivate(sim *Simulation) {
 		oldTime := sim.CurrentTime
 		sim.CurrentTime = min(sim.CurrentTime, aura.expires)
 		aura.metrics.Uptime += sim.CurrentTime - max(aura.startTime, 0)
+		if timeline := aura.Unit.Metrics.timeline; timeline != nil {
+			timeline.addAuraUptime(sim, aura.ActionID, max(aura.startTime, 0), sim.CurrentTime)
+		}
 		if sim.Log != nil {
-			aura.Unit.Log(sim, "Aura faded: %s", aura.ActionID)
+			aura.logEvent(sim, proto.AuraEventType_AuraFaded, 0, 0)
 		}
 		sim.CurrentTime = oldTime
 	}
@@ -0,0 +1,230 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+// BossScriptAI is the TargetAI of targets configured with a proto.BossScript instead of a
+// preset AI. Abilities are used in the order they are declared, whenever they are ready.
+type BossScriptAI struct {
+	Target *Target
+
+	script    *proto.BossScript
+	abilities []*bossScriptAbility
+}
+
+type bossScriptAbility struct {
+	config *proto.BossAbility
+	spell  *Spell
+	phase  *EncounterPhase
+
+	// The spell's cooldown, which rolls its jitter on each use.
+	cd        *Timer
+	cooldown  time.Duration
+	initialCD time.Duration
+	jitter    time.Duration
+
+	// Reset at the start of each iteration.
+	used bool
+}
+
+func NewBossScriptAI(script *proto.BossScript) AIFactory {
+	return func() TargetAI {
+		return &BossScriptAI{
+			script: script,
+		}
+	}
+}
+
+func (ai *BossScriptAI) Initialize(target *Target, _ *proto.Target) {
+	ai.Target = target
+
+	encounter := &target.Env.Encounter
+	for _, phaseConfig := range ai.script.Phases {
+		if encounter.GetPhase(phaseConfig.Name) == nil {
+			encounter.AddPhase(newEncounterPhase(phaseConfig))
+		}
+	}
+
+	for _, config := range ai.script.Abilities {
+		ai.abilities = append(ai.abilities, ai.registerAbility(config))
+	}
+}
+
+func (ai *BossScriptAI) registerAbility(config *proto.BossAbility) *bossScriptAbility {
+	ability := &bossScriptAbility{
+		config:    config,
+		cd:        ai.Target.NewTimer(),
+		cooldown:  DurationFromSeconds(max(config.Cooldown, 0)),
+		initialCD: DurationFromSeconds(max(config.InitialCooldown, 0)),
+		jitter:    DurationFromSeconds(max(config.CooldownJitter, 0)),
+	}
+
+	if config.Phase != "" {
+		ability.phase = ai.Target.Env.Encounter.GetPhase(config.Phase)
+		if ability.phase == nil {
+			panic(fmt.Sprintf("[USER_ERROR] Boss ability %s uses unknown encounter phase %s", config.Name, config.Phase))
+		}
+		ability.phase.OnStart(func(sim *Simulation, _ *EncounterPhase) {
+			ability.cd.Set(sim.CurrentTime + ability.initialCD)
+		})
+	}
+
+	debuffs := ai.registerDebuffs(ability)
+
+	school := SpellSchoolFromProto(config.School)
+	defenseType := DefenseTypeMagic
+	procMask := ProcMaskSpellDamage
+	if school == SpellSchoolPhysical {
+		defenseType = DefenseTypeMelee
+		procMask = ProcMaskMeleeMHSpecial
+	}
+
+	castTime := DurationFromSeconds(max(config.CastTime, 0))
+
+	ability.spell = ai.Target.RegisterSpell(SpellConfig{
+		ActionID:         ActionID{SpellID: config.SpellId},
+		SpellSchool:      school,
+		DefenseType:      defenseType,
+		ProcMask:         procMask,
+		DamageMultiplier: 1,
+
+		Cast: CastConfig{
+			DefaultCast: Cast{
+				GCD:      max(GCDDefault, castTime),
+				CastTime: castTime,
+			},
+			ModifyCast: func(sim *Simulation, spell *Spell, cast *Cast) {
+				if cast.CastTime > 0 {
+					spell.Unit.AutoAttacks.StopMeleeUntil(sim, sim.CurrentTime+cast.CastTime, false)
+				}
+			},
+		},
+
+		ApplyEffects: func(sim *Simulation, target *Unit, spell *Spell) {
+			outcome := spell.OutcomeMagicHit
+			if school == SpellSchoolPhysical {
+				outcome = spell.OutcomeEnemyMeleeWhite
+			}
+
+			targets := []*Unit{target}
+			if config.Target == proto.BossAbilityTarget_BossAbilityTargetAllPlayers {
+				targets = sim.Raid.AllPlayerUnits
+			}
+
+			for _, target := range targets {
+				result := spell.CalcAndDealDamage(sim, target, sim.Roll(config.MinDamage, max(config.MinDamage, config.MaxDamage)), outcome)
+				if debuffs != nil && result.Landed() {
+					debuff := debuffs.Get(target)
+					debuff.Activate(sim)
+					debuff.AddStack(sim)
+				}
+			}
+		},
+	})
+
+	return ability
+}
+
+// Registers the debuff of the ability on all players, or returns nil if it has none.
+func (ai *BossScriptAI) registerDebuffs(ability *bossScriptAbility) AuraArray {
+	debuff := ability.config.Debuff
+	if debuff == nil {
+		return nil
+	}
+
+	label := debuff.Label
+	if label == "" {
+		label = ability.config.Name
+	}
+	tickInterval := DurationFromSeconds(debuff.TickInterval)
+
+	return ai.Target.NewRaidAuraArray(func(unit *Unit) *Aura {
+		var ticks *PendingAction
+
+		return unit.RegisterAura(Aura{
+			Label:     fmt.Sprintf("%s (%s)", label, ai.Target.Label),
+			ActionID:  ActionID{SpellID: debuff.SpellId},
+			Duration:  DurationFromSeconds(debuff.Duration),
+			MaxStacks: max(debuff.MaxStacks, 1),
+			OnGain: func(aura *Aura, sim *Simulation) {
+				if debuff.TickDamage <= 0 || tickInterval <= 0 {
+					return
+				}
+				spell := ability.spell
+				ticks = StartPeriodicAction(sim, PeriodicActionOptions{
+					Period: tickInterval,
+					OnAction: func(sim *Simulation) {
+						spell.CalcAndDealPeriodicDamage(sim, aura.Unit, debuff.TickDamage*float64(aura.GetStacks()), spell.OutcomeAlwaysHit)
+					},
+				})
+			},
+			OnExpire: func(aura *Aura, sim *Simulation) {
+				if ticks != nil {
+					ticks.Cancel(sim)
+					ticks = nil
+				}
+			},
+			OnStacksChange: func(aura *Aura, sim *Simulation, oldStacks int32, newStacks int32) {
+				aura.Unit.PseudoStats.DamageTakenMultiplier *= (1 + debuff.DamageTakenPerStack*float64(newStacks)) / (1 + debuff.DamageTakenPerStack*float64(oldStacks))
+				aura.Unit.PseudoStats.DamageDealtMultiplier *= (1 + debuff.DamageDealtPerStack*float64(newStacks)) / (1 + debuff.DamageDealtPerStack*float64(oldStacks))
+			},
+		})
+	})
+}
+
+func (ai *BossScriptAI) Reset(sim *Simulation) {
+	for _, ability := range ai.abilities {
+		ability.used = false
+		if ability.phase == nil {
+			ability.cd.Set(ability.initialCD)
+		} else {
+			ability.cd.Set(NeverExpires)
+		}
+	}
+}
+
+func (ability *bossScriptAbility) isReady(sim *Simulation) bool {
+	if ability.used || !ability.cd.IsReady(sim) || !ability.spell.IsReady(sim) {
+		return false
+	}
+	return ability.phase == nil || ability.phase.IsStarted()
+}
+
+// Returns the player the ability is cast on. Players which don't tank the boss are still hit
+// by tank abilities, so individual sims see them.
+func (ai *BossScriptAI) abilityTarget(sim *Simulation, ability *bossScriptAbility) *Unit {
+	players := sim.Raid.AllPlayerUnits
+	if ability.config.Target == proto.BossAbilityTarget_BossAbilityTargetRandomPlayer && len(players) > 1 {
+		return players[int(sim.RandomFloat("Boss Ability Target")*float64(len(players)))]
+	}
+	if ai.Target.CurrentTarget != nil {
+		return ai.Target.CurrentTarget
+	}
+	return players[0]
+}
+
+func (ai *BossScriptAI) ExecuteCustomRotation(sim *Simulation) {
+	for _, ability := range ai.abilities {
+		if !ability.isReady(sim) {
+			continue
+		}
+
+		if ability.config.ChanceToUse > 0 && !sim.Proc(ability.config.ChanceToUse, "Boss Ability") {
+			continue
+		}
+
+		ability.spell.Cast(sim, ai.abilityTarget(sim, ability))
+		ability.used = ability.config.Once
+
+		cooldown := ability.cooldown
+		if ability.jitter > 0 {
+			cooldown += time.Duration(sim.RandomFloat("Boss Ability Cooldown") * float64(ability.jitter))
+		}
+		ability.cd.Set(sim.CurrentTime + cooldown)
+		return
+	}
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/wowsims/sod/sim/core/proto"
+	"github.com/wowsims/sod/sim/core/simsignals"
+)
+
+func TestBossScript(t *testing.T) {
+	rsr := fakeSimRequest()
+	rsr.Encounter.Duration = 60
+	rsr.SimOptions.Iterations = 20
+	rsr.Encounter.Targets[0].BossScript = &proto.BossScript{
+		Phases: []*proto.EncounterPhase{
+			{Name: "Burn", Trigger: &proto.EncounterPhase_TimeSeconds{TimeSeconds: 30}},
+		},
+		Abilities: []*proto.BossAbility{
+			{
+				Name:    "Enrage",
+				SpellId: 1002,
+				Phase:   "Burn",
+				Once:    true,
+			},
+			{
+				Name:      "Shadow Bolt",
+				SpellId:   1001,
+				School:    proto.SpellSchool_SpellSchoolShadow,
+				MinDamage: 100,
+				MaxDamage: 200,
+				Cooldown:  10,
+				Debuff: &proto.BossDebuff{
+					SpellId:             1003,
+					Label:               "Shadow Vulnerability",
+					Duration:            15,
+					MaxStacks:           3,
+					DamageTakenPerStack: 0.1,
+				},
+			},
+		},
+	}
+	sim := NewSim(rsr, simsignals.CreateSignals())
+	player := sim.Raid.Parties[0].Players[0].GetCharacter()
+
+	// Bolts at 0s, 10s and 20s, unless one missed.
+	startFakeSimUntil(sim, time.Second*25)
+	debuff := player.GetAuraByID(ActionID{SpellID: 1003})
+	if debuff == nil || !debuff.IsActive() {
+		t.Fatalf("Expected the debuff to be active at %s", sim.CurrentTime)
+	}
+	expected := 1 + 0.1*float64(debuff.GetStacks())
+	if actual := player.PseudoStats.DamageTakenMultiplier; math.Abs(actual-expected) > 1e-9 {
+		t.Errorf("Expected a damage taken multiplier of %0.2f at %d stacks, got %0.4f", expected, debuff.GetStacks(), actual)
+	}
+
+	result := RunRaidSim(rsr)
+	if result.Error != nil {
+		t.Fatalf("Sim failed: %s", result.Error.Message)
+	}
+
+	casts := map[int32]int32{}
+	for _, action := range result.EncounterMetrics.Targets[0].Actions {
+		for _, target := range action.Targets {
+			casts[action.Id.GetSpellId()] += target.Casts
+		}
+	}
+	iterations := rsr.SimOptions.Iterations
+	if casts[1002] != iterations {
+		t.Errorf("Expected Enrage to be cast once per iteration, got %d casts in %d iterations", casts[1002], iterations)
+	}
+	// At 0s, 10s and 20s, then delayed by Enrage to 31.5s, 41.5s and 51.5s.
+	if casts[1001] != 6*iterations {
+		t.Errorf("Expected Shadow Bolt to be cast 6 times per iteration, got %d casts in %d iterations", casts[1001], iterations)
+	}
+}
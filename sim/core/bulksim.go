@@ -3,10 +3,13 @@ package core
 import (
 	"fmt"
 	"math"
+	"math/big"
 	"runtime"
 	"runtime/debug"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -18,6 +21,11 @@ import (
 
 const (
 	defaultIterationsPerCombo = 1000
+	defaultMaxResults         = 30
+	defaultTargetConfidence   = 0.95
+
+	// Upper limit for the number of combos a single bulk sim may request.
+	maxBulkSimCombos = 1000000
 )
 
 // raidSimRunner runs a standard raid simulation.
@@ -29,6 +37,9 @@ type bulkSimRunner struct {
 	SingleRaidSimRunner raidSimRunner
 	// Request used for this bulk simulation.
 	Request *proto.BulkSimRequest
+
+	// Raid index of the player whose settings are varied.
+	raidIndex int32
 }
 
 func BulkSim(signals simsignals.Signals, request *proto.BulkSimRequest, progress chan *proto.ProgressMetrics) *proto.BulkSimResult {
@@ -53,6 +64,8 @@ type singleBulkSim struct {
 	req *proto.RaidSimRequest
 	cl  *raidSimRequestChangeLog
 	eq  *equipmentSubstitution
+	pv  *playerVariant
+	tl  *proto.TalentLoadout
 }
 
 func (b *bulkSimRunner) Run(signals simsignals.Signals, progress chan *proto.ProgressMetrics) (result *proto.BulkSimResult) {
@@ -65,31 +78,45 @@ func (b *bulkSimRunner) Run(signals simsignals.Signals, progress chan *proto.Pro
 		signals.Abort.Trigger()
 	}()
 
-	// Bulk simming is only supported for the single-player use (i.e. not whole raid-wide simming).
-	// Verify that we have exactly 1 player.
+	// Find the player whose settings are varied. Without an explicit reference, this has to be
+	// the only player in the raid.
 	var playerCount int
-	var player *proto.Player
-	for _, p := range b.Request.GetBaseSettings().GetRaid().GetParties() {
-		for _, pl := range p.GetPlayers() {
+	for partyIndex, p := range b.Request.GetBaseSettings().GetRaid().GetParties() {
+		for playerIndex, pl := range p.GetPlayers() {
 			// TODO(Riotdog-GehennasEU): Better way to check if a player is valid/set?
 			if pl.Name != "" {
-				player = pl
+				b.raidIndex = int32(partyIndex*5 + playerIndex)
 				playerCount++
 			}
 		}
 	}
-	if playerCount != 1 || player == nil {
+	if ref := b.Request.GetPlayer(); ref != nil {
+		if ref.Type != proto.UnitReference_Player {
+			return &proto.BulkSimResult{
+				Error: &proto.ErrorOutcome{
+					Message: fmt.Sprintf("bulksim: player reference has type %s, expected %s", ref.Type, proto.UnitReference_Player),
+				},
+			}
+		}
+		b.raidIndex = ref.Index
+		if pl := bulkSimPlayer(b.Request.BaseSettings, b.raidIndex); pl == nil || pl.Name == "" {
+			return &proto.BulkSimResult{
+				Error: &proto.ErrorOutcome{
+					Message: fmt.Sprintf("bulksim: no player with raid index %d", ref.Index),
+				},
+			}
+		}
+	} else if playerCount != 1 {
 		return &proto.BulkSimResult{
 			Error: &proto.ErrorOutcome{
 				Message: fmt.Sprintf("bulksim: expected exactly 1 player, found %d", playerCount),
 			},
 		}
 	}
+	player := bulkSimPlayer(b.Request.BaseSettings, b.raidIndex)
 	if player.GetDatabase() != nil {
 		addToDatabase(player.GetDatabase())
 	}
-	// reduce to just base party.
-	b.Request.BaseSettings.Raid.Parties = []*proto.Party{b.Request.BaseSettings.Raid.Parties[0]}
 	// clean to reduce memory
 	player.Database = nil
 
@@ -131,23 +158,59 @@ func (b *bulkSimRunner) Run(signals simsignals.Signals, progress chan *proto.Pro
 	}
 	baseItems := player.Equipment.Items
 
-	allCombos := generateAllEquipmentSubstitutions(signals, baseItems, b.Request.BulkSettings.Combinations, distinctItemSlotCombos)
+	// Each equipment combo is simmed with the player's current talents (nil loadout) and,
+	// if enabled, once more for every talent loadout to sim.
+	talentLoadouts := []*proto.TalentLoadout{nil}
+	if b.Request.BulkSettings.SimTalents {
+		for _, tl := range b.Request.BulkSettings.TalentsToSim {
+			if tl.GetTalentsString() == "" || tl.GetTalentsString() == player.TalentsString {
+				continue
+			}
+			talentLoadouts = append(talentLoadouts, tl)
+		}
+	}
 
-	var validCombos []singleBulkSim
-	count := 0
-	for sub := range allCombos {
-		count++
-		if count > 1000000 {
-			panic("over 1 million combos, abandoning attempt")
+	for _, ews := range b.Request.BulkSettings.Enchants {
+		if message := validateEnchantWithSlot(ews); message != "" {
+			return &proto.BulkSimResult{
+				Error: &proto.ErrorOutcome{
+					Message: message + " in bulk settings",
+				},
+			}
 		}
-		substitutedRequest, changeLog := createNewRequestWithSubstitution(b.Request.BaseSettings, sub, b.Request.BulkSettings.AutoEnchant)
-		if isValidEquipment(substitutedRequest.Raid.Parties[0].Players[0].Equipment) {
-			validCombos = append(validCombos, singleBulkSim{req: substitutedRequest, cl: changeLog, eq: sub})
+	}
+	for _, rws := range b.Request.BulkSettings.Runes {
+		if message := validateRuneWithSlot(player.Class, rws); message != "" {
+			return &proto.BulkSimResult{
+				Error: &proto.ErrorOutcome{
+					Message: message + " in bulk settings",
+				},
+			}
 		}
 	}
+	variants := generatePlayerVariants(b.Request.BulkSettings)
 
-	// TODO(Riotdog-GehennasEU): Make this configurable?
-	maxResults := 30
+	numCombos := b.countValidCombos(signals, baseItems, distinctItemSlotCombos, variants, talentLoadouts, maxBulkSimCombos)
+	if numCombos > maxBulkSimCombos {
+		requested := estimateCombos(b.Request.BulkSettings.Combinations, baseItems, distinctItemSlotCombos, len(variants), len(talentLoadouts))
+		return &proto.BulkSimResult{
+			Error: &proto.ErrorOutcome{
+				Message: fmt.Sprintf("bulksim: about %s combos requested, at most %d are supported", requested, maxBulkSimCombos),
+			},
+		}
+	}
+
+	maxResults := int(b.Request.BulkSettings.MaxResults)
+	if maxResults <= 0 {
+		maxResults = defaultMaxResults
+	}
+
+	targetConfidence := b.Request.BulkSettings.TargetConfidence
+	if targetConfidence <= 0 || targetConfidence >= 1 {
+		targetConfidence = defaultTargetConfidence
+	}
+	// One-sided z-score for the target confidence.
+	zScore := math.Sqrt2 * math.Erfinv(2*targetConfidence-1)
 
 	var rankedResults []*itemSubstitutionSimResult
 	var baseResult *itemSubstitutionSimResult
@@ -164,18 +227,21 @@ func (b *bulkSimRunner) Run(signals simsignals.Signals, progress chan *proto.Pro
 		}
 	}
 
-	maxIterations := newIters * int64(len(validCombos))
+	maxIterations := newIters * numCombos
 	if maxIterations > math.MaxInt32 {
 		return &proto.BulkSimResult{
 			Error: &proto.ErrorOutcome{Message: fmt.Sprintf("number of total iterations %d too large", maxIterations)},
 		}
 	}
 
+	// Combos are generated while the first round of sims is running.
+	combos := b.streamValidCombos(signals, baseItems, distinctItemSlotCombos, variants, talentLoadouts)
+	expectedCombos := numCombos
+
 	for {
 		var tempBase *itemSubstitutionSimResult
 		var errorOutcome *proto.ErrorOutcome
-		// TODO: we could theoretically make getRankedResults accept a channel of validCombos that stream in to it and launches sims as it gets them...
-		rankedResults, tempBase, errorOutcome = b.getRankedResults(signals, validCombos, newIters, progress)
+		rankedResults, tempBase, errorOutcome = b.getRankedResults(signals, combos, int32(expectedCombos), newIters, progress)
 
 		if errorOutcome != nil {
 			return &proto.BulkSimResult{Error: errorOutcome}
@@ -185,8 +251,8 @@ func (b *bulkSimRunner) Run(signals simsignals.Signals, progress chan *proto.Pro
 			baseResult = tempBase
 		}
 
-		// If we aren't doing fast mode, or if halving our results will be less than the maxResults, be done.
-		if !b.Request.BulkSettings.FastMode || len(rankedResults) <= maxResults*2 {
+		// If we aren't doing fast mode, or if we are already down to maxResults combos, be done.
+		if !b.Request.BulkSettings.FastMode || len(rankedResults) <= maxResults {
 			break
 		}
 
@@ -195,18 +261,11 @@ func (b *bulkSimRunner) Run(signals simsignals.Signals, progress chan *proto.Pro
 			break
 		}
 
-		// Increase accuracy
-		newIters *= 2
-		newNumCombos := len(rankedResults) / 2
-		validCombos = validCombos[:newNumCombos]
-		rankedResults = rankedResults[:newNumCombos]
-		for i, comb := range rankedResults {
-			validCombos[i] = singleBulkSim{
-				req: comb.Request,
-				cl:  comb.ChangeLog,
-				eq:  comb.Substitution,
-			}
-		}
+		// Drop combos that are clearly worse than the leader, then increase accuracy for the rest.
+		rankedResults = pruneRankedResults(rankedResults, maxResults, zScore)
+		newIters = min(newIters*2, int64(iterations))
+		combos = b.streamCombosFromResults(signals, rankedResults)
+		expectedCombos = int64(len(rankedResults))
 	}
 
 	if baseResult == nil {
@@ -221,28 +280,22 @@ func (b *bulkSimRunner) Run(signals simsignals.Signals, progress chan *proto.Pro
 		rankedResults = rankedResults[:maxResults]
 	}
 
-	bum := baseResult.Result.GetRaidMetrics().GetParties()[0].GetPlayers()[0]
-	bum.Actions = nil
-	bum.Auras = nil
-	bum.Resources = nil
-	bum.Pets = nil
-
 	result = &proto.BulkSimResult{
 		EquippedGearResult: &proto.BulkComboResult{
-			UnitMetrics: bum,
+			UnitMetrics: baseResult.PlayerMetrics(b.raidIndex),
+			RaidDps:     baseResult.Result.GetRaidMetrics().GetDps(),
 		},
 	}
 
 	for _, r := range rankedResults {
-		um := r.Result.GetRaidMetrics().GetParties()[0].GetPlayers()[0]
-		um.Actions = nil
-		um.Auras = nil
-		um.Resources = nil
-		um.Pets = nil
-
 		result.Results = append(result.Results, &proto.BulkComboResult{
-			ItemsAdded:  r.ChangeLog.AddedItems,
-			UnitMetrics: um,
+			ItemsAdded:      r.ChangeLog.AddedItems,
+			UnitMetrics:     r.PlayerMetrics(b.raidIndex),
+			RaidDps:         r.Result.GetRaidMetrics().GetDps(),
+			TalentLoadout:   r.TalentLoadout,
+			EnchantsApplied: r.Variant.Enchants,
+			RunesApplied:    r.Variant.Runes,
+			ConsumesApplied: r.Variant.Consumes,
 		})
 	}
 
@@ -255,7 +308,146 @@ func (b *bulkSimRunner) Run(signals simsignals.Signals, progress chan *proto.Pro
 	return result
 }
 
-func (b *bulkSimRunner) getRankedResults(signals simsignals.Signals, validCombos []singleBulkSim, iterations int64, progress chan *proto.ProgressMetrics) ([]*itemSubstitutionSimResult, *itemSubstitutionSimResult, *proto.ErrorOutcome) {
+// streamValidCombos lazily creates the requests for all valid combinations of equipment
+// substitutions, player variants and talent loadouts. Requests are only created once the previous
+// one has been picked up, so at most a few of them are held in memory at once.
+func (b *bulkSimRunner) streamValidCombos(signals simsignals.Signals, baseItems []*proto.ItemSpec, distinctItemSlotCombos []*itemWithSlot, variants []*playerVariant, talentLoadouts []*proto.TalentLoadout) <-chan singleBulkSim {
+	combos := make(chan singleBulkSim)
+	go func() {
+		defer close(combos)
+		for sub := range generateAllEquipmentSubstitutions(signals, baseItems, b.Request.BulkSettings.Combinations, distinctItemSlotCombos) {
+			substitutedRequest, changeLog := createNewRequestWithSubstitution(b.Request.BaseSettings, b.raidIndex, sub, b.Request.BulkSettings.AutoEnchant)
+			if !isValidEquipment(bulkSimPlayer(substitutedRequest, b.raidIndex).Equipment) {
+				continue
+			}
+			for _, pv := range variants {
+				// Without combinations, variants are only simmed with the base equipment.
+				if !b.Request.BulkSettings.Combinations && sub.HasItemReplacements() && pv.HasChanges() {
+					break
+				}
+				variantRequest := createNewRequestWithVariant(substitutedRequest, b.raidIndex, pv)
+				if variantRequest == nil {
+					continue
+				}
+				for _, tl := range talentLoadouts {
+					select {
+					case combos <- singleBulkSim{req: createNewRequestWithTalents(variantRequest, b.raidIndex, tl), cl: changeLog, eq: sub, pv: pv, tl: tl}:
+					case <-signals.Abort.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	return combos
+}
+
+// estimateCombos returns how many combos a bulk sim requests, without generating them. Items are
+// counted once per slot and ring and trinket pairs once regardless of their order, but combos
+// which turn out to be invalid are still included, so the estimate can be higher than the number
+// of combos simmed.
+func estimateCombos(combinations bool, baseItems []*proto.ItemSpec, distinctItemSlotCombos []*itemWithSlot, numVariants int, numTalentLoadouts int) *big.Int {
+	idsBySlot := make([]map[int32]struct{}, len(proto.ItemSlot_name))
+	for i := range idsBySlot {
+		idsBySlot[i] = map[int32]struct{}{}
+	}
+	for _, is := range distinctItemSlotCombos {
+		idsBySlot[is.Slot][is.Item.Id] = struct{}{}
+	}
+
+	count := big.NewInt(0)
+	if !combinations {
+		// The base equipment, every item once, plus pairs of new rings and trinkets, then every
+		// variant with the base equipment.
+		count.SetInt64(int64(numVariants))
+		for _, ids := range idsBySlot {
+			count.Add(count, big.NewInt(int64(len(ids))))
+		}
+		for _, slot := range []proto.ItemSlot{proto.ItemSlot_ItemSlotFinger1, proto.ItemSlot_ItemSlotTrinket1} {
+			count.Add(count, big.NewInt(int64(len(idsBySlot[slot])*len(idsBySlot[slot+1]))))
+		}
+	} else {
+		// Every slot keeps its base item or uses one of its replacements, except that ring and
+		// trinket pairs are unordered pairs of different items.
+		count.SetInt64(int64(numVariants))
+		for slot, ids := range idsBySlot {
+			switch proto.ItemSlot(slot) {
+			case proto.ItemSlot_ItemSlotFinger1, proto.ItemSlot_ItemSlotTrinket1:
+				pairIds := map[int32]struct{}{}
+				for _, pairSlot := range []int{slot, slot + 1} {
+					for id := range idsBySlot[pairSlot] {
+						pairIds[id] = struct{}{}
+					}
+					if id := baseItems[pairSlot].Id; id != 0 {
+						pairIds[id] = struct{}{}
+					}
+				}
+				// Pairs of different items, or one item and an empty slot.
+				n := int64(len(pairIds))
+				count.Mul(count, big.NewInt(max(1, n*(n-1)/2+n)))
+			case proto.ItemSlot_ItemSlotFinger2, proto.ItemSlot_ItemSlotTrinket2:
+				// Counted with the first slot of the pair.
+			default:
+				count.Mul(count, big.NewInt(int64(len(ids)+1)))
+			}
+		}
+	}
+	return count.Mul(count, big.NewInt(int64(numTalentLoadouts)))
+}
+
+// countValidCombos returns the number of combos streamValidCombos produces. Counting stops once
+// more than limit combos have been found, so huge requests are rejected without generating all
+// of their combos.
+func (b *bulkSimRunner) countValidCombos(signals simsignals.Signals, baseItems []*proto.ItemSpec, distinctItemSlotCombos []*itemWithSlot, variants []*playerVariant, talentLoadouts []*proto.TalentLoadout, limit int64) int64 {
+	// The generator is stopped by its own signals once counting is done.
+	countSignals := simsignals.CreateSignals()
+	defer countSignals.Abort.Trigger()
+
+	baseEquipment := bulkSimPlayer(b.Request.BaseSettings, b.raidIndex).Equipment
+	var count int64
+	for sub := range generateAllEquipmentSubstitutions(countSignals, baseItems, b.Request.BulkSettings.Combinations, distinctItemSlotCombos) {
+		if count > limit || signals.Abort.IsTriggered() {
+			break
+		}
+		// Substitutions only replace items, so a copy of the item list is enough.
+		equipment := &proto.EquipmentSpec{Items: slices.Clone(baseEquipment.Items)}
+		applySubstitution(equipment, sub, b.Request.BulkSettings.AutoEnchant)
+		if !isValidEquipment(equipment) {
+			continue
+		}
+		for _, pv := range variants {
+			if !b.Request.BulkSettings.Combinations && sub.HasItemReplacements() && pv.HasChanges() {
+				break
+			}
+			if pv.appliesTo(equipment) {
+				count += int64(len(talentLoadouts))
+			}
+		}
+	}
+	return count
+}
+
+// streamCombosFromResults lazily recreates the requests of previously simmed combos.
+func (b *bulkSimRunner) streamCombosFromResults(signals simsignals.Signals, results []*itemSubstitutionSimResult) <-chan singleBulkSim {
+	combos := make(chan singleBulkSim)
+	go func() {
+		defer close(combos)
+		for _, r := range results {
+			substitutedRequest, _ := createNewRequestWithSubstitution(b.Request.BaseSettings, b.raidIndex, r.Substitution, b.Request.BulkSettings.AutoEnchant)
+			variantRequest := createNewRequestWithVariant(substitutedRequest, b.raidIndex, r.Variant)
+			select {
+			case combos <- singleBulkSim{req: createNewRequestWithTalents(variantRequest, b.raidIndex, r.TalentLoadout), cl: r.ChangeLog, eq: r.Substitution, pv: r.Variant, tl: r.TalentLoadout}:
+			case <-signals.Abort.Done():
+				return
+			}
+		}
+	}()
+	return combos
+}
+
+// getRankedResults sims all combos as they stream in and returns the results ordered by score.
+// expectedCombos is only used for progress reporting until all combos have been received.
+func (b *bulkSimRunner) getRankedResults(signals simsignals.Signals, combos <-chan singleBulkSim, expectedCombos int32, iterations int64, progress chan *proto.ProgressMetrics) ([]*itemSubstitutionSimResult, *itemSubstitutionSimResult, *proto.ErrorOutcome) {
 	concurrency := runtime.NumCPU() + 1
 	if concurrency <= 0 {
 		concurrency = 2
@@ -266,11 +458,9 @@ func (b *bulkSimRunner) getRankedResults(signals simsignals.Signals, validCombos
 		tickets <- struct{}{}
 	}
 
-	results := make(chan *itemSubstitutionSimResult, 10)
-
-	numCombinations := int32(len(validCombos))
-	totalIterationsUpperBound := int64(numCombinations) * iterations
+	results := make(chan *itemSubstitutionSimResult, concurrency)
 
+	numCombinations := expectedCombos
 	var totalCompletedIterations int32
 	var totalCompletedSims int32
 
@@ -279,19 +469,22 @@ func (b *bulkSimRunner) getRankedResults(signals simsignals.Signals, validCombos
 	// reporter for all sims combined.
 	go func() {
 		for !signals.Abort.IsTriggered() && !reporterSignal.Abort.IsTriggered() {
+			numCombos := atomic.LoadInt32(&numCombinations)
 			complIters := atomic.LoadInt32(&totalCompletedIterations)
 			complSims := atomic.LoadInt32(&totalCompletedSims)
 
 			// stop reporting
-			if complIters == int32(totalIterationsUpperBound) || numCombinations == complSims {
+			if numCombos == complSims {
 				return
 			}
 
-			progress <- &proto.ProgressMetrics{
-				TotalSims:           numCombinations,
-				CompletedSims:       complSims,
-				CompletedIterations: complIters,
-				TotalIterations:     int32(totalIterationsUpperBound),
+			if progress != nil {
+				progress <- &proto.ProgressMetrics{
+					TotalSims:           numCombos,
+					CompletedSims:       complSims,
+					CompletedIterations: complIters,
+					TotalIterations:     int32(int64(numCombos) * iterations),
+				}
 			}
 			time.Sleep(time.Second)
 		}
@@ -299,8 +492,12 @@ func (b *bulkSimRunner) getRankedResults(signals simsignals.Signals, validCombos
 
 	// launcher for all combos (limited by concurrency max)
 	go func() {
-		for _, singleCombo := range validCombos {
+		var wg sync.WaitGroup
+		var launched int32
+		for singleCombo := range combos {
 			<-tickets
+			launched++
+			wg.Add(1)
 			singleSimProgress := make(chan *proto.ProgressMetrics)
 			// watches this progress and pushes up to main reporter.
 			go func(prog chan *proto.ProgressMetrics) {
@@ -316,33 +513,46 @@ func (b *bulkSimRunner) getRankedResults(signals simsignals.Signals, validCombos
 			}(singleSimProgress)
 			// actually run the sim in here.
 			go func(sub singleBulkSim) {
+				defer wg.Done()
 				// overwrite the requests iterations with the input for this function.
 				sub.req.SimOptions.Iterations = int32(iterations)
 				results <- &itemSubstitutionSimResult{
-					Request:      sub.req,
-					Result:       b.SingleRaidSimRunner(sub.req, singleSimProgress, false, signals),
-					Substitution: sub.eq,
-					ChangeLog:    sub.cl,
+					Result:        trimBulkSimResult(b.SingleRaidSimRunner(sub.req, singleSimProgress, false, signals), b.raidIndex),
+					Substitution:  sub.eq,
+					ChangeLog:     sub.cl,
+					Variant:       sub.pv,
+					TalentLoadout: sub.tl,
 				}
 				atomic.AddInt32(&totalCompletedSims, 1)
 				tickets <- struct{}{} // when done, allow for new sim to be launched.
 			}(singleCombo)
 		}
+		// All combos are known now, so the progress can report the exact count.
+		atomic.StoreInt32(&numCombinations, launched)
+		wg.Wait()
+		close(results)
 	}()
 
-	rankedResults := make([]*itemSubstitutionSimResult, numCombinations)
+	var rankedResults []*itemSubstitutionSimResult
 	var baseResult *itemSubstitutionSimResult
 
-	for i := range rankedResults {
-		result := <-results
+	for result := range results {
 		if result.Result == nil || result.Result.Error != nil {
 			reporterSignal.Abort.Trigger() // cancel reporter
+			go func() {
+				// Let the remaining sims finish without blocking.
+				for range results {
+				}
+			}()
+			if result.Result == nil {
+				return nil, nil, &proto.ErrorOutcome{Message: "bulksim: sim returned no result"}
+			}
 			return nil, nil, result.Result.Error
 		}
-		if !result.Substitution.HasItemReplacements() {
+		if result.IsBaseCombo() {
 			baseResult = result
 		}
-		rankedResults[i] = result
+		rankedResults = append(rankedResults, result)
 	}
 	reporterSignal.Abort.Trigger() // cancel reporter
 
@@ -352,17 +562,62 @@ func (b *bulkSimRunner) getRankedResults(signals simsignals.Signals, validCombos
 	return rankedResults, baseResult, nil
 }
 
-// itemSubstitutionSimResult stores the request and response of a simulation, along with the used
-// equipment susbstitution and a changelog of which items were added and removed from the base
-// equipment set.
+// trimBulkSimResult drops everything from a sim result that isn't needed for ranking or reporting
+// a combo, so the results of many combos can be kept in memory. Only the raid metrics and the
+// metrics of the player with the given raid index are kept.
+func trimBulkSimResult(result *proto.RaidSimResult, raidIndex int32) *proto.RaidSimResult {
+	if result == nil || result.Error != nil {
+		return result
+	}
+
+	party := result.GetRaidMetrics().GetParties()[raidIndex/5]
+	um := party.GetPlayers()[raidIndex%5]
+	um.Actions = nil
+	um.Auras = nil
+	um.Resources = nil
+	um.Pets = nil
+
+	trimmedParties := make([]*proto.PartyMetrics, raidIndex/5+1)
+	for i := range trimmedParties {
+		trimmedParties[i] = &proto.PartyMetrics{}
+	}
+	trimmedParties[raidIndex/5].Players = make([]*proto.UnitMetrics, raidIndex%5+1)
+	trimmedParties[raidIndex/5].Players[raidIndex%5] = um
+
+	return &proto.RaidSimResult{
+		RaidMetrics: &proto.RaidMetrics{
+			Dps:     result.RaidMetrics.Dps,
+			Hps:     result.RaidMetrics.Hps,
+			Parties: trimmedParties,
+		},
+		IterationsDone: result.IterationsDone,
+	}
+}
+
+// itemSubstitutionSimResult stores the response of a simulation, along with the used
+// equipment susbstitution, a changelog of which items were added and removed from the base
+// equipment set, the player variant and the talent loadout used (nil for the player's current
+// talents).
 type itemSubstitutionSimResult struct {
-	Request      *proto.RaidSimRequest
-	Result       *proto.RaidSimResult
-	Substitution *equipmentSubstitution
-	ChangeLog    *raidSimRequestChangeLog
+	Result        *proto.RaidSimResult
+	Substitution  *equipmentSubstitution
+	ChangeLog     *raidSimRequestChangeLog
+	Variant       *playerVariant
+	TalentLoadout *proto.TalentLoadout
+}
+
+// IsBaseCombo returns true if this result is for the player's unchanged settings.
+func (r *itemSubstitutionSimResult) IsBaseCombo() bool {
+	return !r.Substitution.HasItemReplacements() && !r.Variant.HasChanges() && r.TalentLoadout == nil
 }
 
-// Score used to rank results.
+// PlayerMetrics returns the metrics of the player with the given raid index.
+func (r *itemSubstitutionSimResult) PlayerMetrics(raidIndex int32) *proto.UnitMetrics {
+	return r.Result.GetRaidMetrics().GetParties()[raidIndex/5].GetPlayers()[raidIndex%5]
+}
+
+// Score used to rank results. This is the DPS of the whole raid, so that changes to the player
+// are also valued by how they affect the other players.
 func (r *itemSubstitutionSimResult) Score() float64 {
 	if r.Result == nil || r.Result.Error != nil {
 		return 0
@@ -370,6 +625,38 @@ func (r *itemSubstitutionSimResult) Score() float64 {
 	return r.Result.RaidMetrics.Dps.Avg
 }
 
+// StandardError of the score, i.e. the standard deviation of its mean.
+func (r *itemSubstitutionSimResult) StandardError() float64 {
+	if r.Result == nil || r.Result.Error != nil || r.Result.IterationsDone <= 0 {
+		return 0
+	}
+	return r.Result.RaidMetrics.Dps.Stdev / math.Sqrt(float64(r.Result.IterationsDone))
+}
+
+// IsClearlyBetterThan returns true if this result's score is higher than other's score, with
+// the confidence corresponding to the given one-sided z-score.
+func (r *itemSubstitutionSimResult) IsClearlyBetterThan(other *itemSubstitutionSimResult, zScore float64) bool {
+	delta := r.Score() - other.Score()
+	return delta > zScore*math.Hypot(r.StandardError(), other.StandardError())
+}
+
+// pruneRankedResults removes all results which are clearly worse than the leading (first) result.
+// The best maxResults results are always kept.
+func pruneRankedResults(rankedResults []*itemSubstitutionSimResult, maxResults int, zScore float64) []*itemSubstitutionSimResult {
+	if len(rankedResults) <= maxResults {
+		return rankedResults
+	}
+
+	leader := rankedResults[0]
+	pruned := rankedResults[:maxResults]
+	for _, r := range rankedResults[maxResults:] {
+		if !leader.IsClearlyBetterThan(r, zScore) {
+			pruned = append(pruned, r)
+		}
+	}
+	return pruned
+}
+
 // equipmentSubstitution specifies all items to be used as replacements for the equipped gear.
 type equipmentSubstitution struct {
 	Items []*itemWithSlot
@@ -464,14 +751,28 @@ func isValidEquipment(equipment *proto.EquipmentSpec) bool {
 // base case as well.
 func generateAllEquipmentSubstitutions(signals simsignals.Signals, baseItems []*proto.ItemSpec, combinations bool, distinctItemSlotCombos []*itemWithSlot) chan *equipmentSubstitution {
 	results := make(chan *equipmentSubstitution)
+	// emit sends a substitution, returning false if the bulk sim was aborted instead.
+	emit := func(sub *equipmentSubstitution) bool {
+		if signals.Abort.IsTriggered() {
+			return false
+		}
+		select {
+		case results <- sub:
+			return true
+		case <-signals.Abort.Done():
+			return false
+		}
+	}
 	go func() {
 		defer close(results)
 
 		// No substitutions (base case).
-		results <- &equipmentSubstitution{}
+		if !emit(&equipmentSubstitution{}) {
+			return
+		}
 
 		// Organize everything by slot.
-		itemsBySlot := make([][]*proto.ItemSpec, 17)
+		itemsBySlot := make([][]*proto.ItemSpec, len(proto.ItemSlot_name))
 		for _, is := range distinctItemSlotCombos {
 			itemsBySlot[is.Slot] = append(itemsBySlot[is.Slot], is.Item)
 		}
@@ -492,8 +793,8 @@ func generateAllEquipmentSubstitutions(signals simsignals.Signals, baseItems []*
 					// Handle finger/trinket specially to generate combos
 					switch slotid {
 					case int(proto.ItemSlot_ItemSlotFinger1), int(proto.ItemSlot_ItemSlotTrinket1):
-						if !comboChecker.HasCombo(item.Id, baseItems[slotid+1].Id) {
-							results <- &sub
+						if !comboChecker.HasCombo(item.Id, baseItems[slotid+1].Id) && !emit(&sub) {
+							return
 						}
 						// Generate extra combos
 						subslot := slotid + 1
@@ -502,15 +803,19 @@ func generateAllEquipmentSubstitutions(signals simsignals.Signals, baseItems []*
 								continue
 							}
 							miniCombo := createReplacement(sub, &itemWithSlot{Item: subitem, Slot: proto.ItemSlot(subslot)})
-							results <- &miniCombo
+							if !emit(&miniCombo) {
+								return
+							}
 						}
 					case int(proto.ItemSlot_ItemSlotFinger2), int(proto.ItemSlot_ItemSlotTrinket2):
 						// Ensure we don't have this combo with the base equipment.
-						if !comboChecker.HasCombo(item.Id, baseItems[slotid-1].Id) {
-							results <- &sub
+						if !comboChecker.HasCombo(item.Id, baseItems[slotid-1].Id) && !emit(&sub) {
+							return
 						}
 					default:
-						results <- &sub
+						if !emit(&sub) {
+							return
+						}
 					}
 				}
 			}
@@ -521,7 +826,9 @@ func generateAllEquipmentSubstitutions(signals simsignals.Signals, baseItems []*
 		// the best set of items in your bags.
 		subComboChecker := SubstitutionComboChecker{}
 		for i := 0; i < len(itemsBySlot); i++ {
-			genSlotCombos(proto.ItemSlot(i), baseItems, equipmentSubstitution{}, itemsBySlot, subComboChecker, results)
+			if !genSlotCombos(proto.ItemSlot(i), baseItems, equipmentSubstitution{}, itemsBySlot, subComboChecker, emit) {
+				return
+			}
 		}
 	}()
 
@@ -554,7 +861,9 @@ func shouldSkipCombo(baseItems []*proto.ItemSpec, item *proto.ItemSpec, slot pro
 	return false
 }
 
-func genSlotCombos(slot proto.ItemSlot, baseItems []*proto.ItemSpec, baseRepl equipmentSubstitution, replaceBySlot [][]*proto.ItemSpec, comboChecker SubstitutionComboChecker, results chan *equipmentSubstitution) {
+// genSlotCombos emits all combos of the given base replacements with items from this and later
+// slots. Returns false if emitting was stopped early.
+func genSlotCombos(slot proto.ItemSlot, baseItems []*proto.ItemSpec, baseRepl equipmentSubstitution, replaceBySlot [][]*proto.ItemSpec, comboChecker SubstitutionComboChecker, emit func(*equipmentSubstitution) bool) bool {
 	// Iterate all items in this slot, add to the baseRepl, then descend to add all other item combos.
 	for _, item := range replaceBySlot[slot] {
 		// Create a new equipment substitution from the current replacements plus the new item.
@@ -562,13 +871,148 @@ func genSlotCombos(slot proto.ItemSlot, baseItems []*proto.ItemSpec, baseRepl eq
 		if comboChecker.HasCombo(combo) {
 			continue
 		}
-		results <- &combo
+		if !emit(&combo) {
+			return false
+		}
 
 		// Now descend to each other slot to pair with this combo.
 		for j := slot + 1; int(j) < len(replaceBySlot); j++ {
-			genSlotCombos(j, baseItems, combo, replaceBySlot, comboChecker, results)
+			if !genSlotCombos(j, baseItems, combo, replaceBySlot, comboChecker, emit) {
+				return false
+			}
 		}
 	}
+	return true
+}
+
+// runesByClass holds the runes each class can engrave on its armor, i.e. all runes besides ring runes.
+var runesByClass = map[proto.Class]map[int32]string{
+	proto.Class_ClassDruid:   proto.DruidRune_name,
+	proto.Class_ClassHunter:  proto.HunterRune_name,
+	proto.Class_ClassMage:    proto.MageRune_name,
+	proto.Class_ClassPaladin: proto.PaladinRune_name,
+	proto.Class_ClassPriest:  proto.PriestRune_name,
+	proto.Class_ClassRogue:   proto.RogueRune_name,
+	proto.Class_ClassShaman:  proto.ShamanRune_name,
+	proto.Class_ClassWarlock: proto.WarlockRune_name,
+	proto.Class_ClassWarrior: proto.WarriorRune_name,
+}
+
+// validateRuneWithSlot returns why the class can't engrave the rune on the slot, or an empty
+// string if it can. Ring runes go on either ring, the class runes on any other armor slot.
+func validateEnchantWithSlot(ews *proto.EnchantWithSlot) string {
+	enchant, ok := EnchantsByEffectID[ews.Enchant]
+	if !ok {
+		return fmt.Sprintf("unknown enchant with id %d", ews.Enchant)
+	}
+	if !slices.Contains(eligibleSlotsForEnchant(&enchant), ews.Slot) {
+		return fmt.Sprintf("enchant with id %d on slot %s, which it can't be applied to", ews.Enchant, ews.Slot)
+	}
+	return ""
+}
+
+func validateRuneWithSlot(class proto.Class, rws *proto.RuneWithSlot) string {
+	isRingSlot := rws.Slot == proto.ItemSlot_ItemSlotFinger1 || rws.Slot == proto.ItemSlot_ItemSlotFinger2
+	if _, ok := proto.RingRune_name[rws.Rune]; ok && rws.Rune != 0 {
+		if !isRingSlot {
+			return fmt.Sprintf("ring rune with id %d on slot %s, which is not a ring", rws.Rune, rws.Slot)
+		}
+		return ""
+	}
+	if _, ok := runesByClass[class][rws.Rune]; !ok || rws.Rune == 0 {
+		return fmt.Sprintf("unknown rune with id %d for class %s", rws.Rune, class)
+	}
+	switch rws.Slot {
+	case proto.ItemSlot_ItemSlotHead, proto.ItemSlot_ItemSlotShoulder, proto.ItemSlot_ItemSlotBack,
+		proto.ItemSlot_ItemSlotChest, proto.ItemSlot_ItemSlotWrist, proto.ItemSlot_ItemSlotHands,
+		proto.ItemSlot_ItemSlotWaist, proto.ItemSlot_ItemSlotLegs, proto.ItemSlot_ItemSlotFeet:
+		return ""
+	}
+	return fmt.Sprintf("rune with id %d on slot %s, which can't be engraved", rws.Rune, rws.Slot)
+}
+
+// playerVariant specifies the enchants, runes and consumes to apply on top of the player's
+// (possibly substituted) equipment and consumes.
+type playerVariant struct {
+	Enchants []*proto.EnchantWithSlot
+	Runes    []*proto.RuneWithSlot
+	Consumes *proto.Consumes
+}
+
+// HasChanges returns true if the variant changes anything about the player.
+func (pv *playerVariant) HasChanges() bool {
+	return len(pv.Enchants) > 0 || len(pv.Runes) > 0 || pv.Consumes != nil
+}
+
+// appliesTo returns true if every enchant and rune of the variant goes on an equipped item which
+// doesn't already have it.
+func (pv *playerVariant) appliesTo(equipment *proto.EquipmentSpec) bool {
+	for _, ews := range pv.Enchants {
+		if item := equipment.Items[ews.Slot]; item.Id == 0 || item.Enchant == ews.Enchant {
+			return false
+		}
+	}
+	for _, rws := range pv.Runes {
+		if item := equipment.Items[rws.Slot]; item.Id == 0 || item.Rune == rws.Rune {
+			return false
+		}
+	}
+	return true
+}
+
+// generatePlayerVariants returns all player variants for the given bulk settings. The first
+// variant is always the unchanged player. Without combinations, every alternative enchant, rune
+// and consume is a variant by itself. With combinations, the variants are the cross-product of
+// all alternatives, using at most one enchant and rune per slot.
+func generatePlayerVariants(settings *proto.BulkSettings) []*playerVariant {
+	variants := []*playerVariant{{}}
+
+	if !settings.Combinations {
+		for _, ews := range settings.Enchants {
+			variants = append(variants, &playerVariant{Enchants: []*proto.EnchantWithSlot{ews}})
+		}
+		for _, rws := range settings.Runes {
+			variants = append(variants, &playerVariant{Runes: []*proto.RuneWithSlot{rws}})
+		}
+		for _, consumes := range settings.Consumes {
+			variants = append(variants, &playerVariant{Consumes: consumes})
+		}
+		return variants
+	}
+
+	enchantsBySlot := make([][]*proto.EnchantWithSlot, len(proto.ItemSlot_name))
+	for _, ews := range settings.Enchants {
+		enchantsBySlot[ews.Slot] = append(enchantsBySlot[ews.Slot], ews)
+	}
+	for _, slotEnchants := range enchantsBySlot {
+		for _, pv := range variants {
+			for _, ews := range slotEnchants {
+				newEnchants := append(slices.Clip(pv.Enchants), ews)
+				variants = append(variants, &playerVariant{Enchants: newEnchants})
+			}
+		}
+	}
+
+	runesBySlot := make([][]*proto.RuneWithSlot, len(proto.ItemSlot_name))
+	for _, rws := range settings.Runes {
+		runesBySlot[rws.Slot] = append(runesBySlot[rws.Slot], rws)
+	}
+	for _, slotRunes := range runesBySlot {
+		for _, pv := range variants {
+			for _, rws := range slotRunes {
+				newRunes := append(slices.Clip(pv.Runes), rws)
+				variants = append(variants, &playerVariant{Enchants: pv.Enchants, Runes: newRunes})
+			}
+		}
+	}
+
+	for _, pv := range variants {
+		for _, consumes := range settings.Consumes {
+			variants = append(variants, &playerVariant{Enchants: pv.Enchants, Runes: pv.Runes, Consumes: consumes})
+		}
+	}
+
+	return variants
 }
 
 // itemWithSlot pairs an item with its fixed item slot.
@@ -588,13 +1032,30 @@ type raidSimRequestChangeLog struct {
 	AddedItems []*proto.ItemSpecWithSlot
 }
 
+// bulkSimPlayer returns the player with the given raid index, or nil if there is none.
+func bulkSimPlayer(request *proto.RaidSimRequest, raidIndex int32) *proto.Player {
+	parties := request.GetRaid().GetParties()
+	if raidIndex < 0 || int(raidIndex/5) >= len(parties) {
+		return nil
+	}
+	players := parties[raidIndex/5].GetPlayers()
+	if int(raidIndex%5) >= len(players) {
+		return nil
+	}
+	return players[raidIndex%5]
+}
+
 // createNewRequestWithSubstitution creates a copy of the input RaidSimRequest and applis the given
 // equipment susbstitution to the player's equipment. Copies enchant if specified and possible.
-func createNewRequestWithSubstitution(readonlyInputRequest *proto.RaidSimRequest, substitution *equipmentSubstitution, autoEnchant bool) (*proto.RaidSimRequest, *raidSimRequestChangeLog) {
+func createNewRequestWithSubstitution(readonlyInputRequest *proto.RaidSimRequest, raidIndex int32, substitution *equipmentSubstitution, autoEnchant bool) (*proto.RaidSimRequest, *raidSimRequestChangeLog) {
 	request := goproto.Clone(readonlyInputRequest).(*proto.RaidSimRequest)
+	changeLog := applySubstitution(bulkSimPlayer(request, raidIndex).Equipment, substitution, autoEnchant)
+	return request, changeLog
+}
+
+// applySubstitution replaces the items of the equipment with the ones of the substitution.
+func applySubstitution(equipment *proto.EquipmentSpec, substitution *equipmentSubstitution, autoEnchant bool) *raidSimRequestChangeLog {
 	changeLog := &raidSimRequestChangeLog{}
-	player := request.Raid.Parties[0].Players[0]
-	equipment := player.Equipment
 	for _, is := range substitution.Items {
 		oldItem := equipment.Items[is.Slot]
 		if autoEnchant && oldItem.Enchant > 0 && is.Item.Enchant == 0 {
@@ -617,7 +1078,44 @@ func createNewRequestWithSubstitution(readonlyInputRequest *proto.RaidSimRequest
 			})
 		}
 	}
-	return request, changeLog
+	return changeLog
+}
+
+// createNewRequestWithTalents returns the input RaidSimRequest with the player's talents replaced
+// by the given talent loadout. The input request is returned unchanged if no loadout is given.
+func createNewRequestWithTalents(request *proto.RaidSimRequest, raidIndex int32, talentLoadout *proto.TalentLoadout) *proto.RaidSimRequest {
+	if talentLoadout == nil {
+		return request
+	}
+	request = goproto.Clone(request).(*proto.RaidSimRequest)
+	bulkSimPlayer(request, raidIndex).TalentsString = talentLoadout.TalentsString
+	return request
+}
+
+// createNewRequestWithVariant returns the input RaidSimRequest with the given player variant
+// applied. The input request is returned unchanged if the variant has no changes, and nil is
+// returned if the variant doesn't apply to the request's equipment.
+func createNewRequestWithVariant(request *proto.RaidSimRequest, raidIndex int32, variant *playerVariant) *proto.RaidSimRequest {
+	if !variant.HasChanges() {
+		return request
+	}
+	if !variant.appliesTo(bulkSimPlayer(request, raidIndex).Equipment) {
+		return nil
+	}
+	request = goproto.Clone(request).(*proto.RaidSimRequest)
+	player := bulkSimPlayer(request, raidIndex)
+
+	for _, ews := range variant.Enchants {
+		player.Equipment.Items[ews.Slot].Enchant = ews.Enchant
+	}
+	for _, rws := range variant.Runes {
+		player.Equipment.Items[rws.Slot].Rune = rws.Rune
+	}
+	if variant.Consumes != nil {
+		player.Consumes = goproto.Clone(variant.Consumes).(*proto.Consumes)
+	}
+
+	return request
 }
 
 type ItemComboChecker map[int64]struct{}
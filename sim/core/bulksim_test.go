@@ -1,6 +1,8 @@
 package core
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -113,6 +115,457 @@ func TestBulkSim(t *testing.T) {
 	}
 }
 
+func TestBulkSimTalentLoadouts(t *testing.T) {
+	dpsByTalents := map[string]float64{
+		"base":  100,
+		"worse": 50,
+		"best":  200,
+	}
+	fakeRunSim := func(rsr *proto.RaidSimRequest, progress chan *proto.ProgressMetrics, skipPresim bool, signals simsignals.Signals) *proto.RaidSimResult {
+		dps := &proto.DistributionMetrics{Avg: dpsByTalents[rsr.Raid.Parties[0].Players[0].TalentsString]}
+		return &proto.RaidSimResult{
+			RaidMetrics: &proto.RaidMetrics{
+				Dps: dps,
+				Parties: []*proto.PartyMetrics{{
+					Players: []*proto.UnitMetrics{{Dps: dps}},
+				}},
+			},
+		}
+	}
+
+	worse := &proto.TalentLoadout{Name: "Worse", TalentsString: "worse"}
+	best := &proto.TalentLoadout{Name: "Best", TalentsString: "best"}
+	bulk := &bulkSimRunner{
+		SingleRaidSimRunner: fakeRunSim,
+		Request: &proto.BulkSimRequest{
+			BaseSettings: &proto.RaidSimRequest{
+				Raid: &proto.Raid{
+					Parties: []*proto.Party{{
+						Players: []*proto.Player{{
+							Name:          "Player",
+							TalentsString: "base",
+							Equipment:     createEquipmentFromItems(),
+						}},
+					}},
+				},
+				SimOptions: &proto.SimOptions{},
+			},
+			BulkSettings: &proto.BulkSettings{
+				SimTalents:   true,
+				TalentsToSim: []*proto.TalentLoadout{worse, best},
+			},
+		},
+	}
+
+	got := bulk.Run(simsignals.CreateSignals(), nil)
+	if got.Error != nil {
+		t.Fatalf("BulkSim() returned error: %v", got.Error.Message)
+	}
+	if got.EquippedGearResult.UnitMetrics.Dps.Avg != 100 {
+		t.Fatalf("BulkSim() equipped gear result has dps %f, want 100", got.EquippedGearResult.UnitMetrics.Dps.Avg)
+	}
+
+	wantLoadouts := []*proto.TalentLoadout{best, nil, worse}
+	if len(got.Results) != len(wantLoadouts) {
+		t.Fatalf("BulkSim() returned %d results, want %d", len(got.Results), len(wantLoadouts))
+	}
+	for i, want := range wantLoadouts {
+		if got.Results[i].TalentLoadout != want {
+			t.Errorf("BulkSim() result %d used talent loadout %v, want %v", i, got.Results[i].TalentLoadout, want)
+		}
+	}
+}
+
+func TestBulkSimPlayerVariants(t *testing.T) {
+	const (
+		itemHelm        = 46000
+		enchantHelm     = 2500
+		enchantBetter   = 2501
+		runeHelm        = int32(proto.MageRune_RuneHelmHotStreak)
+		flaskOfTheTitan = proto.Flask_FlaskOfTheTitans
+	)
+	addToDatabase(&proto.SimDatabase{
+		Items: []*proto.SimItem{{Id: itemHelm, Type: proto.ItemType_ItemTypeHead}},
+		Enchants: []*proto.SimEnchant{
+			{EffectId: enchantHelm, Type: proto.ItemType_ItemTypeHead},
+			{EffectId: enchantBetter, Type: proto.ItemType_ItemTypeHead},
+		},
+	})
+
+	fakeRunSim := func(rsr *proto.RaidSimRequest, progress chan *proto.ProgressMetrics, skipPresim bool, signals simsignals.Signals) *proto.RaidSimResult {
+		player := rsr.Raid.Parties[0].Players[0]
+		avg := 100.0
+		if player.Equipment.Items[proto.ItemSlot_ItemSlotHead].Enchant == enchantBetter {
+			avg += 10
+		}
+		if player.Equipment.Items[proto.ItemSlot_ItemSlotHead].Rune == runeHelm {
+			avg += 20
+		}
+		if player.Consumes.GetFlask() == flaskOfTheTitan {
+			avg += 40
+		}
+		dps := &proto.DistributionMetrics{Avg: avg}
+		return &proto.RaidSimResult{
+			RaidMetrics: &proto.RaidMetrics{
+				Dps:     dps,
+				Parties: []*proto.PartyMetrics{{Players: []*proto.UnitMetrics{{Dps: dps}}}},
+			},
+		}
+	}
+
+	equipment := createEquipmentFromItems(&itemWithSlot{Item: &proto.ItemSpec{Id: itemHelm, Enchant: enchantHelm}, Slot: proto.ItemSlot_ItemSlotHead})
+	newRequest := func(combinations bool) *proto.BulkSimRequest {
+		return &proto.BulkSimRequest{
+			BaseSettings: &proto.RaidSimRequest{
+				Raid: &proto.Raid{
+					Parties: []*proto.Party{{
+						Players: []*proto.Player{{Name: "Player", Class: proto.Class_ClassMage, Equipment: equipment}},
+					}},
+				},
+				SimOptions: &proto.SimOptions{},
+			},
+			BulkSettings: &proto.BulkSettings{
+				Combinations: combinations,
+				Enchants:     []*proto.EnchantWithSlot{{Enchant: enchantBetter, Slot: proto.ItemSlot_ItemSlotHead}},
+				Runes:        []*proto.RuneWithSlot{{Rune: runeHelm, Slot: proto.ItemSlot_ItemSlotHead}},
+				Consumes:     []*proto.Consumes{{Flask: flaskOfTheTitan}},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		comment      string
+		combinations bool
+		wantDps      []float64
+	}{
+		{
+			comment:      "one change at a time",
+			combinations: false,
+			wantDps:      []float64{140, 120, 110, 100},
+		},
+		{
+			comment:      "all combinations",
+			combinations: true,
+			wantDps:      []float64{170, 160, 150, 140, 130, 120, 110, 100},
+		},
+	} {
+		bulk := &bulkSimRunner{
+			SingleRaidSimRunner: fakeRunSim,
+			Request:             newRequest(tc.combinations),
+		}
+
+		got := bulk.Run(simsignals.CreateSignals(), nil)
+		if got.Error != nil {
+			t.Fatalf("%s: BulkSim() returned error: %v", tc.comment, got.Error.Message)
+		}
+		if len(got.Results) != len(tc.wantDps) {
+			t.Fatalf("%s: BulkSim() returned %d results, want %d", tc.comment, len(got.Results), len(tc.wantDps))
+		}
+		for i, want := range tc.wantDps {
+			if dps := got.Results[i].UnitMetrics.Dps.Avg; dps != want {
+				t.Errorf("%s: BulkSim() result %d has dps %f, want %f", tc.comment, i, dps, want)
+			}
+		}
+
+		best := got.Results[0]
+		if (len(best.EnchantsApplied) == 1) != tc.combinations || (len(best.RunesApplied) == 1) != tc.combinations || best.ConsumesApplied == nil {
+			t.Errorf("%s: BulkSim() best result reports changes %v, %v, %v", tc.comment, best.EnchantsApplied, best.RunesApplied, best.ConsumesApplied)
+		}
+	}
+}
+
+func TestBulkSimInvalidRunes(t *testing.T) {
+	for _, tc := range []struct {
+		rune *proto.RuneWithSlot
+		want string
+	}{
+		{
+			rune: &proto.RuneWithSlot{Rune: 123, Slot: proto.ItemSlot_ItemSlotHead},
+			want: "unknown rune with id 123 for class ClassMage in bulk settings",
+		},
+		{
+			rune: &proto.RuneWithSlot{Rune: int32(proto.WarriorRune_RuneFlagellation), Slot: proto.ItemSlot_ItemSlotHead},
+			want: "unknown rune with id 402877 for class ClassMage in bulk settings",
+		},
+		{
+			rune: &proto.RuneWithSlot{Rune: int32(proto.MageRune_RuneHelmHotStreak), Slot: proto.ItemSlot_ItemSlotTrinket1},
+			want: "rune with id 400624 on slot ItemSlotTrinket1, which can't be engraved in bulk settings",
+		},
+		{
+			rune: &proto.RuneWithSlot{Rune: int32(proto.RingRune_RuneRingFireSpecialization), Slot: proto.ItemSlot_ItemSlotHead},
+			want: "ring rune with id 442894 on slot ItemSlotHead, which is not a ring in bulk settings",
+		},
+	} {
+		bulk := &bulkSimRunner{
+			SingleRaidSimRunner: func(*proto.RaidSimRequest, chan *proto.ProgressMetrics, bool, simsignals.Signals) *proto.RaidSimResult {
+				return &proto.RaidSimResult{Error: &proto.ErrorOutcome{Message: "sim should not run"}}
+			},
+			Request: &proto.BulkSimRequest{
+				BaseSettings: &proto.RaidSimRequest{
+					Raid: &proto.Raid{
+						Parties: []*proto.Party{{
+							Players: []*proto.Player{{Name: "Player", Class: proto.Class_ClassMage, Equipment: createEquipmentFromItems()}},
+						}},
+					},
+					SimOptions: &proto.SimOptions{},
+				},
+				BulkSettings: &proto.BulkSettings{
+					Runes: []*proto.RuneWithSlot{tc.rune},
+				},
+			},
+		}
+
+		got := bulk.Run(simsignals.CreateSignals(), nil)
+		if got.Error == nil || got.Error.Message != tc.want {
+			t.Errorf("BulkSim() returned error %v, want %q", got.Error, tc.want)
+		}
+	}
+}
+
+func TestBulkSimInvalidEnchants(t *testing.T) {
+	const enchantKit = 2502
+	addToDatabase(&proto.SimDatabase{
+		Enchants: []*proto.SimEnchant{{EffectId: enchantKit, Type: proto.ItemType_ItemTypeChest, ExtraTypes: []proto.ItemType{proto.ItemType_ItemTypeLegs}}},
+	})
+
+	for _, tc := range []struct {
+		enchant *proto.EnchantWithSlot
+		want    string
+	}{
+		{
+			enchant: &proto.EnchantWithSlot{Enchant: 123, Slot: proto.ItemSlot_ItemSlotHead},
+			want:    "unknown enchant with id 123 in bulk settings",
+		},
+		{
+			enchant: &proto.EnchantWithSlot{Enchant: enchantKit, Slot: proto.ItemSlot_ItemSlotFeet},
+			want:    "enchant with id 2502 on slot ItemSlotFeet, which it can't be applied to in bulk settings",
+		},
+		{
+			enchant: &proto.EnchantWithSlot{Enchant: enchantKit, Slot: proto.ItemSlot_ItemSlotLegs},
+			want:    "",
+		},
+	} {
+		bulk := &bulkSimRunner{
+			SingleRaidSimRunner: func(*proto.RaidSimRequest, chan *proto.ProgressMetrics, bool, simsignals.Signals) *proto.RaidSimResult {
+				return &proto.RaidSimResult{Error: &proto.ErrorOutcome{Message: "sim should not run"}}
+			},
+			Request: &proto.BulkSimRequest{
+				BaseSettings: &proto.RaidSimRequest{
+					Raid: &proto.Raid{
+						Parties: []*proto.Party{{
+							Players: []*proto.Player{{Name: "Player", Class: proto.Class_ClassMage, Equipment: createEquipmentFromItems()}},
+						}},
+					},
+					SimOptions: &proto.SimOptions{},
+				},
+				BulkSettings: &proto.BulkSettings{
+					Enchants: []*proto.EnchantWithSlot{tc.enchant},
+				},
+			},
+		}
+
+		// Valid enchants get as far as running the sim.
+		want := tc.want
+		if want == "" {
+			want = "sim should not run"
+		}
+		got := bulk.Run(simsignals.CreateSignals(), nil)
+		if got.Error == nil || !strings.Contains(got.Error.Message, want) {
+			t.Errorf("BulkSim() returned error %v, want %q", got.Error, want)
+		}
+	}
+}
+
+func TestBulkSimVariantReplacesConsumes(t *testing.T) {
+	request := &proto.RaidSimRequest{
+		Raid: &proto.Raid{
+			Parties: []*proto.Party{{
+				Players: []*proto.Player{{
+					Name:      "Player",
+					Equipment: createEquipmentFromItems(),
+					Consumes:  &proto.Consumes{Flask: proto.Flask_FlaskOfTheTitans, Food: proto.Food_FoodGrilledSquid},
+				}},
+			}},
+		},
+	}
+	variant := &playerVariant{Consumes: &proto.Consumes{Food: proto.Food_FoodSmokedDesertDumpling}}
+
+	got := createNewRequestWithVariant(request, 0, variant).Raid.Parties[0].Players[0].Consumes
+	if got.Flask != proto.Flask_FlaskUnknown || got.Food != proto.Food_FoodSmokedDesertDumpling {
+		t.Errorf("createNewRequestWithVariant() applied consumes %v, want only the variant's %v", got, variant.Consumes)
+	}
+	if base := request.Raid.Parties[0].Players[0].Consumes; base.Flask != proto.Flask_FlaskOfTheTitans {
+		t.Errorf("createNewRequestWithVariant() changed the base request's consumes to %v", base)
+	}
+}
+
+func TestBulkSimRaidPlayer(t *testing.T) {
+	// The varied player's talents double the DPS of the other player, but lower their own.
+	fakeRunSim := func(rsr *proto.RaidSimRequest, progress chan *proto.ProgressMetrics, skipPresim bool, signals simsignals.Signals) *proto.RaidSimResult {
+		otherDps, ownDps := 100.0, 100.0
+		if rsr.Raid.Parties[1].Players[2].TalentsString == "support" {
+			otherDps, ownDps = 200, 80
+		}
+		return &proto.RaidSimResult{
+			RaidMetrics: &proto.RaidMetrics{
+				Dps: &proto.DistributionMetrics{Avg: otherDps + ownDps},
+				Parties: []*proto.PartyMetrics{
+					{Players: []*proto.UnitMetrics{{Name: "Other", Dps: &proto.DistributionMetrics{Avg: otherDps}}}},
+					{Players: []*proto.UnitMetrics{{}, {}, {Name: "Varied", Dps: &proto.DistributionMetrics{Avg: ownDps}}}},
+				},
+			},
+		}
+	}
+
+	support := &proto.TalentLoadout{Name: "Support", TalentsString: "support"}
+	request := &proto.BulkSimRequest{
+		BaseSettings: &proto.RaidSimRequest{
+			Raid: &proto.Raid{
+				Parties: []*proto.Party{
+					{Players: []*proto.Player{{Name: "Other", Equipment: createEquipmentFromItems()}}},
+					{Players: []*proto.Player{{}, {}, {Name: "Varied", Equipment: createEquipmentFromItems()}}},
+				},
+			},
+			SimOptions: &proto.SimOptions{},
+		},
+		BulkSettings: &proto.BulkSettings{
+			SimTalents:   true,
+			TalentsToSim: []*proto.TalentLoadout{support},
+		},
+	}
+
+	bulk := &bulkSimRunner{SingleRaidSimRunner: fakeRunSim, Request: request}
+	if got := bulk.Run(simsignals.CreateSignals(), nil); got.Error == nil {
+		t.Fatalf("BulkSim() without player reference returned no error for 2 players")
+	}
+
+	request.Player = &proto.UnitReference{Type: proto.UnitReference_Player, Index: 7}
+	bulk = &bulkSimRunner{SingleRaidSimRunner: fakeRunSim, Request: request}
+	got := bulk.Run(simsignals.CreateSignals(), nil)
+	if got.Error != nil {
+		t.Fatalf("BulkSim() returned error: %v", got.Error.Message)
+	}
+	if len(got.Results) != 2 {
+		t.Fatalf("BulkSim() returned %d results, want 2", len(got.Results))
+	}
+
+	best := got.Results[0]
+	if best.TalentLoadout != support {
+		t.Errorf("BulkSim() best result used talent loadout %v, want %v", best.TalentLoadout, support)
+	}
+	if best.UnitMetrics.Name != "Varied" || best.UnitMetrics.Dps.Avg != 80 {
+		t.Errorf("BulkSim() best result has unit metrics for %s with dps %f, want Varied with 80", best.UnitMetrics.Name, best.UnitMetrics.Dps.Avg)
+	}
+	if best.RaidDps.Avg != 280 || got.EquippedGearResult.RaidDps.Avg != 200 {
+		t.Errorf("BulkSim() reported raid dps %f and %f for the base, want 280 and 200", best.RaidDps.Avg, got.EquippedGearResult.RaidDps.Avg)
+	}
+}
+
+func TestBulkSimTooManyCombos(t *testing.T) {
+	const (
+		itemOneHander     = 45000
+		itemFirstMainHand = 90000
+	)
+	db := &proto.SimDatabase{
+		Items: []*proto.SimItem{
+			{Id: itemOneHander, Type: proto.ItemType_ItemTypeWeapon, HandType: proto.HandType_HandTypeOneHand},
+		},
+	}
+	mainHands := make([]*proto.ItemSpec, 1000)
+	for i := range mainHands {
+		mainHands[i] = &proto.ItemSpec{Id: int32(itemFirstMainHand + i)}
+		db.Items = append(db.Items, &proto.SimItem{Id: mainHands[i].Id, Type: proto.ItemType_ItemTypeWeapon, HandType: proto.HandType_HandTypeMainHand})
+	}
+	addToDatabase(db)
+
+	newBulk := func(items []*proto.ItemSpec, talentLoadouts []*proto.TalentLoadout) *bulkSimRunner {
+		return &bulkSimRunner{
+			SingleRaidSimRunner: func(*proto.RaidSimRequest, chan *proto.ProgressMetrics, bool, simsignals.Signals) *proto.RaidSimResult {
+				dps := &proto.DistributionMetrics{Avg: 100}
+				return &proto.RaidSimResult{
+					RaidMetrics: &proto.RaidMetrics{
+						Dps:     dps,
+						Parties: []*proto.PartyMetrics{{Players: []*proto.UnitMetrics{{Dps: dps}}}},
+					},
+				}
+			},
+			Request: &proto.BulkSimRequest{
+				BaseSettings: &proto.RaidSimRequest{
+					Raid: &proto.Raid{
+						Parties: []*proto.Party{{
+							Players: []*proto.Player{{Name: "Player", Equipment: createEquipmentFromItems()}},
+						}},
+					},
+					SimOptions: &proto.SimOptions{},
+				},
+				BulkSettings: &proto.BulkSettings{
+					Items:        items,
+					Combinations: true,
+					SimTalents:   len(talentLoadouts) > 0,
+					TalentsToSim: talentLoadouts,
+					MaxResults:   10,
+				},
+			},
+		}
+	}
+
+	// 1001 main-hand choices with 1000 talent loadouts each.
+	talentLoadouts := make([]*proto.TalentLoadout, 999)
+	for i := range talentLoadouts {
+		talentLoadouts[i] = &proto.TalentLoadout{TalentsString: fmt.Sprintf("talents%d", i)}
+	}
+	got := newBulk(mainHands, talentLoadouts).Run(simsignals.CreateSignals(), nil)
+	want := "bulksim: about 1001000 combos requested, at most 1000000 are supported"
+	if got.Error == nil || got.Error.Message != want {
+		t.Fatalf("BulkSim() returned error %v, want %q", got.Error, want)
+	}
+
+	// Copies of the same one-hander only make for 4 distinct combos, however many are given.
+	oneHanders := make([]*proto.ItemSpec, 1000)
+	for i := range oneHanders {
+		oneHanders[i] = &proto.ItemSpec{Id: itemOneHander}
+	}
+	got = newBulk(oneHanders, nil).Run(simsignals.CreateSignals(), nil)
+	if got.Error != nil {
+		t.Fatalf("BulkSim() returned error: %v", got.Error.Message)
+	}
+	if len(got.Results) != 4 {
+		t.Errorf("BulkSim() returned %d results, want 4", len(got.Results))
+	}
+}
+
+func TestPruneRankedResults(t *testing.T) {
+	newResult := func(avg float64, stdev float64) *itemSubstitutionSimResult {
+		return &itemSubstitutionSimResult{
+			Result: &proto.RaidSimResult{
+				RaidMetrics:    &proto.RaidMetrics{Dps: &proto.DistributionMetrics{Avg: avg, Stdev: stdev}},
+				IterationsDone: 100,
+			},
+		}
+	}
+
+	leader := newResult(1000, 100)
+	closeBehind := newResult(990, 100)
+	noisy := newResult(900, 1000)
+	behind := newResult(900, 100)
+	farBehind := newResult(500, 100)
+
+	got := pruneRankedResults([]*itemSubstitutionSimResult{leader, closeBehind, noisy, behind, farBehind}, 1, 1.645)
+	want := []*itemSubstitutionSimResult{leader, closeBehind, noisy}
+	if len(got) != len(want) {
+		t.Fatalf("pruneRankedResults() kept %d results, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("pruneRankedResults() result %d has score %f, want %f", i, got[i].Score(), want[i].Score())
+		}
+	}
+
+	if got := pruneRankedResults([]*itemSubstitutionSimResult{leader, farBehind}, 2, 1.645); len(got) != 2 {
+		t.Errorf("pruneRankedResults() kept %d results, want the top 2", len(got))
+	}
+}
+
 func TestGenerateAllEquipmentSubstitutions(t *testing.T) {
 	baseItems := make([]*proto.ItemSpec, len(proto.ItemSlot_name))
 	for i := range baseItems {
@@ -380,3 +833,29 @@ func TestGenerateAllEquipmentSubstitutions(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateAllEquipmentSubstitutionsAbort(t *testing.T) {
+	baseItems := make([]*proto.ItemSpec, len(proto.ItemSlot_name))
+	for i := range baseItems {
+		baseItems[i] = &proto.ItemSpec{Id: int32(i) + 1000}
+	}
+	var distinctItemSlotCombos []*itemWithSlot
+	for slot := range baseItems {
+		distinctItemSlotCombos = append(distinctItemSlotCombos, &itemWithSlot{Item: &proto.ItemSpec{Id: int32(slot) + 1}, Slot: proto.ItemSlot(slot)})
+	}
+
+	signals := simsignals.CreateSignals()
+	results := generateAllEquipmentSubstitutions(signals, baseItems, true, distinctItemSlotCombos)
+	<-results
+	<-results
+	signals.Abort.Trigger()
+
+	// At most one more substitution may already be on its way, after which the channel must be closed.
+	count := 0
+	for range results {
+		count++
+	}
+	if count > 1 {
+		t.Fatalf("generateAllEquipmentSubstitutions() emitted %d substitutions after abort", count)
+	}
+}
@@ -17,6 +17,13 @@ type Hardcast struct {
 	ActionID   ActionID
 	OnComplete func(*Simulation, *Unit)
 	Target     *Unit
+
+	// Where the cast put the GCD, and where its cooldowns were before it started. Used to undo the
+	// cooldowns if the cast is interrupted.
+	spell           *Spell
+	gcdReadyAt      time.Duration
+	cdReadyAt       time.Duration
+	sharedCDReadyAt time.Duration
 }
 
 // Input for constructing the CastSpell function for a spell.
@@ -146,6 +153,34 @@ func (unit *Unit) applySpellPushback() {
 	})
 }
 
+// Stops the current hardcast without applying its effects or spending its cost, e.g. when the unit starts moving.
+func (unit *Unit) InterruptHardcast(sim *Simulation) {
+	if !unit.IsCasting(sim) {
+		return
+	}
+
+	if sim.Log != nil {
+		unit.Log(sim, "Cast %s interrupted", unit.Hardcast.ActionID)
+	}
+
+	hc := unit.Hardcast
+	unit.Hardcast = Hardcast{Expires: startingCDTime}
+	if unit.hardcastAction != nil {
+		unit.hardcastAction.Cancel(sim)
+	}
+
+	// Cooldowns only start once a cast finishes, but the GCD started with the cast.
+	if spell := hc.spell; spell != nil {
+		if spell.CD.Timer != nil {
+			spell.CD.Set(hc.cdReadyAt)
+		}
+		if spell.SharedCD.Timer != nil {
+			spell.SharedCD.Set(hc.sharedCDReadyAt)
+		}
+	}
+	unit.SetGCDTimer(sim, max(hc.gcdReadyAt, sim.CurrentTime))
+}
+
 func (spell *Spell) makeCastFunc(config CastConfig) CastSuccessFunc {
 	return func(sim *Simulation, target *Unit) bool {
 		spell.CurCast = spell.DefaultCast
@@ -186,12 +221,14 @@ func (spell *Spell) makeCastFunc(config CastConfig) CastSuccessFunc {
 			spell.CurCast.CastTime = config.CastTime(spell)
 		}
 
+		var cdReadyAt, sharedCDReadyAt time.Duration
 		if config.CD.Timer != nil {
 			// By panicking if spell is on CD, we force each sim to properly check for their own CDs.
 			if !spell.CD.IsReady(sim) {
 				return spell.castFailureHelper(sim, "still on cooldown for %s, curTime = %s", spell.CD.TimeToReady(sim), sim.CurrentTime)
 			}
 
+			cdReadyAt = spell.CD.ReadyAt()
 			spell.CD.Set(sim.CurrentTime + spell.CurCast.CastTime + spell.CD.GetCurrentDuration())
 		}
 
@@ -201,6 +238,7 @@ func (spell *Spell) makeCastFunc(config CastConfig) CastSuccessFunc {
 				return spell.castFailureHelper(sim, "still on shared cooldown for %s, curTime = %s", spell.SharedCD.TimeToReady(sim), sim.CurrentTime)
 			}
 
+			sharedCDReadyAt = spell.SharedCD.ReadyAt()
 			spell.SharedCD.Set(sim.CurrentTime + spell.CurCast.CastTime + spell.SharedCD.Duration)
 		}
 
@@ -243,8 +281,7 @@ func (spell *Spell) makeCastFunc(config CastConfig) CastSuccessFunc {
 		// Hardcasts
 		if spell.CurCast.CastTime > 0 {
 			if sim.Log != nil && !spell.Flags.Matches(SpellFlagNoLogs) {
-				spell.Unit.Log(sim, "Casting %s (Cost = %0.03f, Cast Time = %s, Effective Time = %s)",
-					spell.ActionID, max(0, spell.CurCast.Cost), spell.CurCast.CastTime, spell.CurCast.EffectiveTime())
+				spell.Unit.logCastStart(sim, spell.ActionID, max(0, spell.CurCast.Cost), spell.CurCast.CastTime, spell.CurCast.EffectiveTime())
 			}
 
 			spell.Unit.Hardcast = Hardcast{
@@ -254,7 +291,7 @@ func (spell *Spell) makeCastFunc(config CastConfig) CastSuccessFunc {
 					spell.LastCastAt = sim.CurrentTime
 
 					if sim.Log != nil && !spell.Flags.Matches(SpellFlagNoLogs) {
-						spell.Unit.Log(sim, "Completed cast %s", spell.ActionID)
+						spell.Unit.logCastFinish(sim, spell.ActionID)
 					}
 
 					if spell.Cost != nil {
@@ -276,6 +313,11 @@ func (spell *Spell) makeCastFunc(config CastConfig) CastSuccessFunc {
 					}
 				},
 				Target: target,
+
+				spell:           spell,
+				gcdReadyAt:      sim.CurrentTime + (&Cast{GCD: spell.CurCast.GCD, GCDMin: spell.CurCast.GCDMin}).EffectiveTime(),
+				cdReadyAt:       cdReadyAt,
+				sharedCDReadyAt: sharedCDReadyAt,
 			}
 
 			spell.Unit.newHardcastAction(sim)
@@ -286,9 +328,8 @@ func (spell *Spell) makeCastFunc(config CastConfig) CastSuccessFunc {
 		spell.LastCastAt = sim.CurrentTime
 
 		if sim.Log != nil && !spell.Flags.Matches(SpellFlagNoLogs) {
-			spell.Unit.Log(sim, "Casting %s (Cost = %0.03f, Cast Time = %s, Effective Time = %s)",
-				spell.ActionID, max(0, spell.CurCast.Cost), spell.CurCast.CastTime, spell.CurCast.EffectiveTime())
-			spell.Unit.Log(sim, "Completed cast %s", spell.ActionID)
+			spell.Unit.logCastStart(sim, spell.ActionID, max(0, spell.CurCast.Cost), spell.CurCast.CastTime, spell.CurCast.EffectiveTime())
+			spell.Unit.logCastFinish(sim, spell.ActionID)
 		}
 
 		if spell.Cost != nil {
@@ -336,9 +377,8 @@ func (spell *Spell) makeCastFuncSimple() CastSuccessFunc {
 		}
 
 		if sim.Log != nil && !spell.Flags.Matches(SpellFlagNoLogs) {
-			spell.Unit.Log(sim, "Casting %s (Cost = %0.03f, Cast Time = %s, Effective Time = %s)",
-				spell.ActionID, 0.0, "0s", "0s")
-			spell.Unit.Log(sim, "Completed cast %s", spell.ActionID)
+			spell.Unit.logCastStart(sim, spell.ActionID, 0, 0, 0)
+			spell.Unit.logCastFinish(sim, spell.ActionID)
 		}
 
 		spell.applyEffects(sim, target)
@@ -354,9 +394,8 @@ func (spell *Spell) makeCastFuncSimple() CastSuccessFunc {
 func (spell *Spell) makeCastFuncAutosOrProcs() CastSuccessFunc {
 	return func(sim *Simulation, target *Unit) bool {
 		if sim.Log != nil && !spell.Flags.Matches(SpellFlagNoLogs) {
-			spell.Unit.Log(sim, "Casting %s (Cost = %0.03f, Cast Time = %s, Effective Time = %s)",
-				spell.ActionID, 0.0, "0s", "0s")
-			spell.Unit.Log(sim, "Completed cast %s", spell.ActionID)
+			spell.Unit.logCastStart(sim, spell.ActionID, 0, 0, 0)
+			spell.Unit.logCastFinish(sim, spell.ActionID)
 		}
 
 		spell.applyEffects(sim, target)
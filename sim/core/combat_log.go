@@ -0,0 +1,286 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+// LogEvent records a structured combat log event for unit, which may be nil
+// for events that don't belong to a unit. Only call this when sim.Log != nil.
+func (sim *Simulation) LogEvent(unit *Unit, event *proto.CombatLogEvent) {
+	event.Timestamp = sim.CurrentTime.Seconds()
+	if unit != nil {
+		event.Unit = unit.LogLabel()
+	}
+	sim.combatLog = append(sim.combatLog, event)
+
+	// Uncomment this to print logs directly to console.
+	// fmt.Print(FormatCombatLogEvent(event))
+}
+
+func (unit *Unit) logCastStart(sim *Simulation, actionID ActionID, cost float64, castTime time.Duration, effectiveTime time.Duration) {
+	sim.LogEvent(unit, &proto.CombatLogEvent{Event: &proto.CombatLogEvent_CastStart{CastStart: &proto.CastStartEvent{
+		ActionId:      actionID.ToProto(),
+		Cost:          cost,
+		CastTime:      castTime.Seconds(),
+		EffectiveTime: effectiveTime.Seconds(),
+	}}})
+}
+
+func (unit *Unit) logCastFinish(sim *Simulation, actionID ActionID) {
+	sim.LogEvent(unit, &proto.CombatLogEvent{Event: &proto.CombatLogEvent_CastFinish{CastFinish: &proto.CastFinishEvent{
+		ActionId: actionID.ToProto(),
+	}}})
+}
+
+func (spell *Spell) logSpellResult(sim *Simulation, result *SpellResult, isPeriodic bool, isHealing bool) {
+	outcome, resistPercent := result.Outcome.toProto()
+	spellResult := &proto.SpellResultEvent{
+		Target:        result.Target.LogLabel(),
+		ActionId:      spell.ActionID.ToProto(),
+		IsTick:        isPeriodic,
+		Outcome:       outcome,
+		ResistPercent: resistPercent,
+		Amount:        result.Damage,
+		SpellSchool:   int32(spell.SpellSchool),
+		Threat:        result.Threat,
+	}
+
+	if isHealing {
+		sim.LogEvent(spell.Unit, &proto.CombatLogEvent{Event: &proto.CombatLogEvent_Healing{Healing: spellResult}})
+	} else {
+		sim.LogEvent(spell.Unit, &proto.CombatLogEvent{Event: &proto.CombatLogEvent_Damage{Damage: spellResult}})
+	}
+}
+
+func (aura *Aura) logEvent(sim *Simulation, eventType proto.AuraEventType, oldStacks int32, newStacks int32) {
+	sim.LogEvent(aura.Unit, &proto.CombatLogEvent{Event: &proto.CombatLogEvent_Aura{Aura: &proto.AuraEvent{
+		ActionId:  aura.ActionID.ToProto(),
+		Type:      eventType,
+		OldStacks: oldStacks,
+		NewStacks: newStacks,
+	}}})
+}
+
+func (unit *Unit) logResourceChange(sim *Simulation, resourceType proto.ResourceType, actionID ActionID, spent bool, amount float64, oldValue float64, newValue float64) {
+	unit.logResourceChangeOn(sim, nil, resourceType, actionID, spent, amount, oldValue, newValue)
+}
+
+// logResourceChangeOn is logResourceChange for resources that are kept on a
+// target, i.e. combo points.
+func (unit *Unit) logResourceChangeOn(sim *Simulation, target *Unit, resourceType proto.ResourceType, actionID ActionID, spent bool, amount float64, oldValue float64, newValue float64) {
+	resource := &proto.ResourceEvent{
+		Type:     resourceType,
+		ActionId: actionID.ToProto(),
+		Spent:    spent,
+		Amount:   amount,
+		OldValue: oldValue,
+		NewValue: newValue,
+	}
+	if target != nil {
+		resource.Target = target.LogLabel()
+	}
+	sim.LogEvent(unit, &proto.CombatLogEvent{Event: &proto.CombatLogEvent_Resource{Resource: resource}})
+}
+
+func (pet *Pet) logEnabled(sim *Simulation, enabled bool) {
+	sim.LogEvent(&pet.Unit, &proto.CombatLogEvent{Event: &proto.CombatLogEvent_Pet{Pet: &proto.PetEvent{
+		Enabled: enabled,
+	}}})
+}
+
+func (ho HitOutcome) toProto() (proto.SpellOutcome, int32) {
+	resistPercent := int32(0)
+	if ho.Matches(OutcomePartial1_4) {
+		resistPercent = 25
+	} else if ho.Matches(OutcomePartial2_4) {
+		resistPercent = 50
+	} else if ho.Matches(OutcomePartial3_4) {
+		resistPercent = 75
+	}
+
+	// Matches the precedence of HitOutcome.String().
+	if ho.Matches(OutcomeMiss) {
+		return proto.SpellOutcome_SpellOutcomeMiss, resistPercent
+	} else if ho.Matches(OutcomeDodge) {
+		return proto.SpellOutcome_SpellOutcomeDodge, resistPercent
+	} else if ho.Matches(OutcomeParry) {
+		return proto.SpellOutcome_SpellOutcomeParry, resistPercent
+	} else if ho.Matches(OutcomeGlance) {
+		return proto.SpellOutcome_SpellOutcomeGlance, resistPercent
+	} else if ho.Matches(OutcomeBlock) && ho.Matches(OutcomeCrit) {
+		return proto.SpellOutcome_SpellOutcomeBlockedCrit, resistPercent
+	} else if ho.Matches(OutcomeBlock) {
+		return proto.SpellOutcome_SpellOutcomeBlock, resistPercent
+	} else if ho.Matches(OutcomeCrit) {
+		return proto.SpellOutcome_SpellOutcomeCrit, resistPercent
+	} else if ho.Matches(OutcomeHit) {
+		return proto.SpellOutcome_SpellOutcomeHit, resistPercent
+	} else if ho.Matches(OutcomeCrush) {
+		return proto.SpellOutcome_SpellOutcomeCrush, resistPercent
+	} else {
+		return proto.SpellOutcome_SpellOutcomeEmpty, resistPercent
+	}
+}
+
+// FormatCombatLog renders events as the text log, one line per event.
+func FormatCombatLog(events []*proto.CombatLogEvent) string {
+	var sb strings.Builder
+	for _, event := range events {
+		sb.WriteString(FormatCombatLogEvent(event))
+	}
+	return sb.String()
+}
+
+// FormatCombatLogEvent renders a single event as a line of the text log.
+func FormatCombatLogEvent(event *proto.CombatLogEvent) string {
+	message := formatCombatLogMessage(event)
+	if event.Unit != "" {
+		message = event.Unit + " " + message
+	}
+	return fmt.Sprintf("[%0.2f] %s\n", event.Timestamp, message)
+}
+
+func formatCombatLogMessage(event *proto.CombatLogEvent) string {
+	switch e := event.Event.(type) {
+	case *proto.CombatLogEvent_Message:
+		return e.Message
+	case *proto.CombatLogEvent_CastStart:
+		return fmt.Sprintf("Casting %s (Cost = %0.03f, Cast Time = %s, Effective Time = %s)",
+			ProtoToActionID(e.CastStart.ActionId), e.CastStart.Cost, secondsToDuration(e.CastStart.CastTime), secondsToDuration(e.CastStart.EffectiveTime))
+	case *proto.CombatLogEvent_CastFinish:
+		return fmt.Sprintf("Completed cast %s", ProtoToActionID(e.CastFinish.ActionId))
+	case *proto.CombatLogEvent_Damage:
+		result := e.Damage
+		outcome := formatSpellOutcome(result.Outcome, result.ResistPercent)
+		if spellOutcomeLanded(result.Outcome) {
+			outcome = fmt.Sprintf("%s for %0.3f damage", outcome, result.Amount)
+		}
+		tick := ""
+		if result.IsTick {
+			tick = " tick"
+		}
+		return fmt.Sprintf("%s %s%s %s (SpellSchool: %d). (Threat: %0.3f)", result.Target, ProtoToActionID(result.ActionId), tick, outcome, result.SpellSchool, result.Threat)
+	case *proto.CombatLogEvent_Healing:
+		result := e.Healing
+		outcome := fmt.Sprintf("%s for %0.3f healing", formatSpellOutcome(result.Outcome, result.ResistPercent), result.Amount)
+		tick := ""
+		if result.IsTick {
+			tick = " tick"
+		}
+		return fmt.Sprintf("%s %s%s %s. (Threat: %0.3f)", result.Target, ProtoToActionID(result.ActionId), tick, outcome, result.Threat)
+	case *proto.CombatLogEvent_Aura:
+		actionID := ProtoToActionID(e.Aura.ActionId)
+		switch e.Aura.Type {
+		case proto.AuraEventType_AuraGained:
+			return fmt.Sprintf("Aura gained: %s", actionID)
+		case proto.AuraEventType_AuraFaded:
+			return fmt.Sprintf("Aura faded: %s", actionID)
+		case proto.AuraEventType_AuraRefreshed:
+			return fmt.Sprintf("Aura refreshed: %s", actionID)
+		default:
+			return fmt.Sprintf("%s stacks: %d --> %d", actionID, e.Aura.OldStacks, e.Aura.NewStacks)
+		}
+	case *proto.CombatLogEvent_Resource:
+		return formatResourceEvent(e.Resource)
+	case *proto.CombatLogEvent_Pet:
+		if e.Pet.Enabled {
+			return "Pet summoned"
+		}
+		return "Pet dismissed"
+	default:
+		return ""
+	}
+}
+
+func formatResourceEvent(resource *proto.ResourceEvent) string {
+	actionID := ProtoToActionID(resource.ActionId)
+	verb := "Gained"
+	if resource.Spent {
+		verb = "Spent"
+	}
+
+	if resource.Type != proto.ResourceType_ResourceTypeComboPoints {
+		return fmt.Sprintf("%s %0.3f %s from %s (%0.3f --> %0.3f).", verb, resource.Amount, resourceName(resource.Type), actionID, resource.OldValue, resource.NewValue)
+	}
+
+	amount, oldValue, newValue := int32(resource.Amount), int32(resource.OldValue), int32(resource.NewValue)
+	switch {
+	case !resource.Spent:
+		return fmt.Sprintf("Gained %d combo points on %s from %s (%d --> %d)", amount, resource.Target, actionID, oldValue, newValue)
+	case resource.Target != "":
+		return fmt.Sprintf("Spent %d combo points on %s from %s (%d --> %d) (target swap)", amount, resource.Target, actionID, oldValue, newValue)
+	default:
+		return fmt.Sprintf("Spent %d combo points from %s (%d --> %d).", amount, actionID, oldValue, newValue)
+	}
+}
+
+func resourceName(resourceType proto.ResourceType) string {
+	switch resourceType {
+	case proto.ResourceType_ResourceTypeMana:
+		return "mana"
+	case proto.ResourceType_ResourceTypeEnergy:
+		return "energy"
+	case proto.ResourceType_ResourceTypeRage:
+		return "rage"
+	case proto.ResourceType_ResourceTypeComboPoints:
+		return "combo points"
+	case proto.ResourceType_ResourceTypeFocus:
+		return "focus"
+	case proto.ResourceType_ResourceTypeHealth:
+		return "health"
+	default:
+		return "resource"
+	}
+}
+
+func formatSpellOutcome(outcome proto.SpellOutcome, resistPercent int32) string {
+	var name string
+	switch outcome {
+	case proto.SpellOutcome_SpellOutcomeMiss:
+		return "Miss"
+	case proto.SpellOutcome_SpellOutcomeDodge:
+		return "Dodge"
+	case proto.SpellOutcome_SpellOutcomeParry:
+		return "Parry"
+	case proto.SpellOutcome_SpellOutcomeBlockedCrit:
+		return "BlockedCrit"
+	case proto.SpellOutcome_SpellOutcomeBlock:
+		return "Block"
+	case proto.SpellOutcome_SpellOutcomeCrush:
+		return "Crush"
+	case proto.SpellOutcome_SpellOutcomeGlance:
+		name = "Glance"
+	case proto.SpellOutcome_SpellOutcomeCrit:
+		name = "Crit"
+	case proto.SpellOutcome_SpellOutcomeHit:
+		name = "Hit"
+	default:
+		return "Empty"
+	}
+
+	if resistPercent > 0 {
+		return fmt.Sprintf("%s (%d%% Resist)", name, resistPercent)
+	}
+	return name
+}
+
+func spellOutcomeLanded(outcome proto.SpellOutcome) bool {
+	switch outcome {
+	case proto.SpellOutcome_SpellOutcomeHit, proto.SpellOutcome_SpellOutcomeCrit, proto.SpellOutcome_SpellOutcomeCrush,
+		proto.SpellOutcome_SpellOutcomeGlance, proto.SpellOutcome_SpellOutcomeBlock, proto.SpellOutcome_SpellOutcomeBlockedCrit:
+		return true
+	default:
+		return false
+	}
+}
+
+// secondsToDuration undoes time.Duration.Seconds(), rounding away the floating
+// point error so the text log prints the same durations the sim used.
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Round(seconds * float64(time.Second)))
+}
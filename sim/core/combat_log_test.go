@@ -0,0 +1,98 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+func TestCombatLogSpellOutcomeText(t *testing.T) {
+	outcomes := []HitOutcome{OutcomeEmpty, OutcomeMiss, OutcomeHit, OutcomeDodge, OutcomeGlance, OutcomeParry, OutcomeBlock, OutcomeBlock | OutcomeCrit, OutcomeCrit, OutcomeCrush}
+	partials := []HitOutcome{OutcomeEmpty, OutcomePartial1_4, OutcomePartial2_4, OutcomePartial3_4}
+
+	for _, outcome := range outcomes {
+		for _, partial := range partials {
+			ho := outcome | partial
+			if got := formatSpellOutcome(ho.toProto()); got != ho.String() {
+				t.Errorf("Outcome %d formatted as %q, expected %q", ho, got, ho.String())
+			}
+			spellOutcome, _ := ho.toProto()
+			if landed := spellOutcomeLanded(spellOutcome); landed != ho.Matches(OutcomeLanded) {
+				t.Errorf("Outcome %q landed = %t, expected %t", ho.String(), landed, !landed)
+			}
+		}
+	}
+}
+
+func TestCombatLogEventText(t *testing.T) {
+	actionID := ActionID{SpellID: 11286}
+	events := []struct {
+		event    *proto.CombatLogEvent
+		expected string
+	}{
+		{
+			event: &proto.CombatLogEvent{Timestamp: 1.5, Unit: "[Rogue]", Event: &proto.CombatLogEvent_CastStart{CastStart: &proto.CastStartEvent{
+				ActionId: actionID.ToProto(), Cost: 35, CastTime: (1500 * time.Millisecond).Seconds(), EffectiveTime: time.Second.Seconds(),
+			}}},
+			expected: "[1.50] [Rogue] Casting {SpellID: 11286} (Cost = 35.000, Cast Time = 1.5s, Effective Time = 1s)\n",
+		},
+		{
+			event: &proto.CombatLogEvent{Timestamp: 2, Unit: "[Rogue]", Event: &proto.CombatLogEvent_Resource{Resource: &proto.ResourceEvent{
+				Type: proto.ResourceType_ResourceTypeComboPoints, ActionId: actionID.ToProto(), Amount: 1, OldValue: 2, NewValue: 3, Target: "[Target 1]",
+			}}},
+			expected: "[2.00] [Rogue] Gained 1 combo points on [Target 1] from {SpellID: 11286} (2 --> 3)\n",
+		},
+		{
+			event: &proto.CombatLogEvent{Timestamp: 2, Unit: "[Rogue]", Event: &proto.CombatLogEvent_Resource{Resource: &proto.ResourceEvent{
+				Type: proto.ResourceType_ResourceTypeComboPoints, ActionId: actionID.ToProto(), Spent: true, Amount: 3, OldValue: 3,
+			}}},
+			expected: "[2.00] [Rogue] Spent 3 combo points from {SpellID: 11286} (3 --> 0).\n",
+		},
+		{
+			event: &proto.CombatLogEvent{Timestamp: 3, Unit: "[Rogue]", Event: &proto.CombatLogEvent_Damage{Damage: &proto.SpellResultEvent{
+				Target: "[Target 1]", ActionId: actionID.ToProto(), IsTick: true, Outcome: proto.SpellOutcome_SpellOutcomeHit, ResistPercent: 25, Amount: 100, SpellSchool: 8, Threat: 100,
+			}}},
+			expected: "[3.00] [Rogue] [Target 1] {SpellID: 11286} tick Hit (25% Resist) for 100.000 damage (SpellSchool: 8). (Threat: 100.000)\n",
+		},
+		{
+			event: &proto.CombatLogEvent{Timestamp: 4, Unit: "[Rogue]", Event: &proto.CombatLogEvent_Aura{Aura: &proto.AuraEvent{
+				ActionId: actionID.ToProto(), Type: proto.AuraEventType_AuraStacksChanged, OldStacks: 1, NewStacks: 2,
+			}}},
+			expected: "[4.00] [Rogue] {SpellID: 11286} stacks: 1 --> 2\n",
+		},
+		{
+			event:    &proto.CombatLogEvent{Timestamp: 5, Event: &proto.CombatLogEvent_Message{Message: "Sim-wide message"}},
+			expected: "[5.00] Sim-wide message\n",
+		},
+	}
+
+	for _, e := range events {
+		if got := FormatCombatLogEvent(e.event); got != e.expected {
+			t.Errorf("Formatted event as %q, expected %q", got, e.expected)
+		}
+	}
+}
+
+func TestCombatLogOnlyOnce(t *testing.T) {
+	for _, structured := range []bool{false, true} {
+		rsr := fakeSimRequest()
+		rsr.Raid.Parties[0].Players[0].Rotation = fakeDotRotation()
+		rsr.Encounter.Duration = 30
+		rsr.SimOptions.Iterations = 10
+		rsr.SimOptions.Debug = true
+		rsr.SimOptions.StructuredCombatLog = structured
+
+		for name, result := range map[string]*proto.RaidSimResult{
+			"single":     RunRaidSim(rsr),
+			"concurrent": RunRaidSimConcurrent(rsr),
+		} {
+			if result.Error != nil {
+				t.Fatalf("%s sim failed: %s", name, result.Error.Message)
+			}
+			if hasText, hasEvents := result.Logs != "", len(result.CombatLog) > 0; hasText == structured || hasEvents != structured {
+				t.Errorf("%s sim with structured combat log %t returned text logs: %t, combat log events: %t", name, structured, hasText, hasEvents)
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/wowsims/sod/sim/core/proto"
+	"github.com/wowsims/sod/sim/core/simsignals"
+	googleProto "google.golang.org/protobuf/proto"
+)
+
+// P-value below which a SimComparison is significant.
+const compareSimsSignificance = 0.05
+
+func compareSims(request *proto.CompareSimsRequest, signals simsignals.Signals) *proto.CompareSimsResult {
+	if request.Base == nil {
+		return &proto.CompareSimsResult{Error: &proto.ErrorOutcome{Message: "compare: missing base raid sim request"}}
+	}
+	if len(request.Variants) == 0 {
+		return &proto.CompareSimsResult{Error: &proto.ErrorOutcome{Message: "compare: no variants to compare to base"}}
+	}
+
+	raidIndex := int32(-1)
+	if ref := request.GetPlayer(); ref != nil {
+		if ref.Type != proto.UnitReference_Player {
+			return &proto.CompareSimsResult{
+				Error: &proto.ErrorOutcome{
+					Message: fmt.Sprintf("compare: player reference has type %s, expected %s", ref.Type, proto.UnitReference_Player),
+				},
+			}
+		}
+		for i, rsr := range append([]*proto.RaidSimRequest{request.Base}, request.Variants...) {
+			if pl := bulkSimPlayer(rsr, ref.Index); pl == nil || pl.Name == "" {
+				return &proto.CompareSimsResult{
+					Error: &proto.ErrorOutcome{
+						Message: fmt.Sprintf("compare: no player with raid index %d in request %d", ref.Index, i),
+					},
+				}
+			}
+		}
+		raidIndex = ref.Index
+	}
+
+	simOptions := googleProto.Clone(request.Base.GetSimOptions()).(*proto.SimOptions)
+	if simOptions.Iterations <= 0 {
+		return &proto.CompareSimsResult{Error: &proto.ErrorOutcome{Message: "compare: iterations must be positive"}}
+	}
+	simOptions.SaveAllValues = true
+	// Each iteration is reseeded from the seed, see reseedRands, so all requests need the same one.
+	// Without a user-supplied seed it still needs to be random, so that run-run differences exist.
+	if simOptions.RandomSeed == 0 {
+		simOptions.RandomSeed = time.Now().UnixNano()
+	}
+	// Keeps the iterations paired even when a variant makes a different number of random rolls.
+	simOptions.UseLabeledRands = true
+
+	simFunc := runSimConcurrent
+	// Don't use go threads in wasm, it just adds more overhead and makes the worker more unresponsive.
+	if IsRunningInWasm() || simOptions.IsTest {
+		simFunc = RunSim
+	}
+
+	runIterations := func(rsr *proto.RaidSimRequest) ([]float64, *proto.ErrorOutcome) {
+		rsr = googleProto.Clone(rsr).(*proto.RaidSimRequest)
+		rsr.SimOptions = simOptions
+		result := simFunc(rsr, nil, signals)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if raidIndex >= 0 {
+			return result.RaidMetrics.Parties[raidIndex/5].Players[raidIndex%5].Dps.AllValues, nil
+		}
+		return result.RaidMetrics.Dps.AllValues, nil
+	}
+
+	baseValues, err := runIterations(request.Base)
+	if err != nil {
+		return &proto.CompareSimsResult{Error: err}
+	}
+
+	var baseAgg aggregator
+	for _, value := range baseValues {
+		baseAgg.add(value)
+	}
+	result := &proto.CompareSimsResult{
+		BaseDps:    baseAgg.sum / float64(baseAgg.n),
+		Iterations: int32(baseAgg.n),
+	}
+	for _, variant := range request.Variants {
+		values, err := runIterations(variant)
+		if err != nil {
+			return &proto.CompareSimsResult{Error: err}
+		}
+		result.Variants = append(result.Variants, pairedComparison(baseValues, values))
+	}
+	return result
+}
+
+// Compares the per-iteration values of a variant to those of base, iteration by iteration.
+// Using the differences cancels out most of the RNG noise both runs have in common.
+func pairedComparison(baseValues []float64, values []float64) *proto.SimComparison {
+	n := min(len(baseValues), len(values))
+	var valueAgg, diffAgg aggregator
+	for i := 0; i < n; i++ {
+		valueAgg.add(values[i])
+		diffAgg.add(values[i] - baseValues[i])
+	}
+	diff := diffAgg.sum / float64(n)
+
+	comparison := &proto.SimComparison{
+		Dps:     valueAgg.sum / float64(n),
+		DpsDiff: diff,
+		PValue:  1,
+	}
+	if n > 1 {
+		// Sample variance of the differences, clamped against rounding errors when they are all equal.
+		variance := max(0, (diffAgg.sumSq-float64(n)*diff*diff)/float64(n-1))
+		comparison.DpsDiffStderr = math.Sqrt(variance / float64(n))
+	}
+
+	if comparison.DpsDiffStderr > 0 {
+		// Normal approximation of the paired t-test, which is close enough for the usual iteration counts.
+		z := math.Abs(diff) / comparison.DpsDiffStderr
+		comparison.PValue = math.Erfc(z / math.Sqrt2)
+	} else if diff != 0 {
+		comparison.PValue = 0
+	}
+	comparison.Significant = comparison.PValue < compareSimsSignificance
+
+	return comparison
+}
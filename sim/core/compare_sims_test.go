@@ -0,0 +1,53 @@
+package core
+
+import (
+	"math"
+	"testing"
+
+	"github.com/wowsims/sod/sim/core/proto"
+	"github.com/wowsims/sod/sim/core/stats"
+)
+
+func TestCompareSims(t *testing.T) {
+	base := replayTestRequest(false)
+	base.SimOptions.IsTest = true
+
+	same := replayTestRequest(false)
+	better := replayTestRequest(false)
+	bonusStats := stats.Stats{}
+	bonusStats[stats.SpellPower] = 10
+	better.Raid.Parties[0].Players[0].BonusStats = &proto.UnitStats{Stats: bonusStats[:]}
+
+	result := CompareSims(&proto.CompareSimsRequest{
+		Base:     base,
+		Variants: []*proto.RaidSimRequest{same, better},
+		Player:   &proto.UnitReference{Type: proto.UnitReference_Player, Index: 0},
+	})
+	if result.Error != nil {
+		t.Fatalf("Compare failed: %s", result.Error.Message)
+	}
+	if result.Iterations != base.SimOptions.Iterations || len(result.Variants) != 2 {
+		t.Fatalf("Expected 2 comparisons over %d iterations, got %d over %d", base.SimOptions.Iterations, len(result.Variants), result.Iterations)
+	}
+
+	if same := result.Variants[0]; same.DpsDiff != 0 || same.DpsDiffStderr != 0 || same.Significant {
+		t.Errorf("Expected no difference to an identical setup, got %+v", same)
+	}
+	if better := result.Variants[1]; better.DpsDiff <= 0 || !better.Significant || math.Abs(better.Dps-result.BaseDps-better.DpsDiff) > 1e-6 {
+		t.Errorf("Expected a significant DPS increase from spell power, got %+v", better)
+	}
+}
+
+func TestCompareSimsErrors(t *testing.T) {
+	rsr := replayTestRequest(false)
+	requests := []*proto.CompareSimsRequest{
+		{Variants: []*proto.RaidSimRequest{rsr}},
+		{Base: rsr},
+		{Base: rsr, Variants: []*proto.RaidSimRequest{rsr}, Player: &proto.UnitReference{Type: proto.UnitReference_Player, Index: 3}},
+	}
+	for _, request := range requests {
+		if result := CompareSims(request); result.Error == nil {
+			t.Errorf("Expected an error comparing %v", request)
+		}
+	}
+}
@@ -36,8 +36,16 @@ func addToDatabase(newDB *proto.SimDatabase) {
 
 	for _, v := range newDB.Enchants {
 		rwMutex.Lock()
-		if _, ok := EnchantsByEffectID[v.EffectId]; !ok {
+		if enchant, ok := EnchantsByEffectID[v.EffectId]; !ok {
 			EnchantsByEffectID[v.EffectId] = EnchantFromProto(v)
+		} else {
+			// Enchants on different types of items can share an effect ID.
+			for _, itemType := range append([]proto.ItemType{v.Type}, v.ExtraTypes...) {
+				if itemType != enchant.Type && !slices.Contains(enchant.ExtraTypes, itemType) {
+					enchant.ExtraTypes = append(enchant.ExtraTypes, itemType)
+				}
+			}
+			EnchantsByEffectID[v.EffectId] = enchant
 		}
 		rwMutex.Unlock()
 	}
@@ -138,14 +146,18 @@ func RandomSuffixFromProto(pData *proto.ItemRandomSuffix) RandomSuffix {
 }
 
 type Enchant struct {
-	EffectID int32 // Used by UI to apply effect to tooltip
-	Stats    stats.Stats
+	EffectID   int32 // Used by UI to apply effect to tooltip
+	Stats      stats.Stats
+	Type       proto.ItemType
+	ExtraTypes []proto.ItemType
 }
 
 func EnchantFromProto(pData *proto.SimEnchant) Enchant {
 	return Enchant{
-		EffectID: pData.EffectId,
-		Stats:    stats.FromFloatArray(pData.Stats),
+		EffectID:   pData.EffectId,
+		Stats:      stats.FromFloatArray(pData.Stats),
+		Type:       pData.Type,
+		ExtraTypes: slices.Clone(pData.ExtraTypes),
 	}
 }
 
@@ -520,3 +532,15 @@ func eligibleSlotsForItem(item *Item) []proto.ItemSlot {
 
 	return nil
 }
+
+func eligibleSlotsForEnchant(enchant *Enchant) []proto.ItemSlot {
+	var slots []proto.ItemSlot
+	for _, itemType := range append([]proto.ItemType{enchant.Type}, enchant.ExtraTypes...) {
+		if itemType == proto.ItemType_ItemTypeWeapon {
+			slots = append(slots, proto.ItemSlot_ItemSlotMainHand, proto.ItemSlot_ItemSlotOffHand)
+		} else {
+			slots = append(slots, itemTypeToSlotsMap[itemType]...)
+		}
+	}
+	return slots
+}
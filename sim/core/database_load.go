@@ -44,8 +44,10 @@ func init() {
 
 	for i, enchant := range db.Enchants {
 		simDB.Enchants[i] = &proto.SimEnchant{
-			EffectId: enchant.EffectId,
-			Stats:    enchant.Stats,
+			EffectId:   enchant.EffectId,
+			Stats:      enchant.Stats,
+			Type:       enchant.Type,
+			ExtraTypes: enchant.ExtraTypes,
 		}
 	}
 
@@ -27,6 +27,8 @@ func init() {
 type FakeAgent struct {
 	Spell *Spell
 	Dot   *Dot
+	// A spell with a cast time and a cooldown.
+	CastSpell *Spell
 	Character
 	Init func()
 }
@@ -88,13 +90,36 @@ func NewFakeElementalShaman(char *Character, _ *proto.Player) Agent {
 			},
 		})
 		fa.Dot = fa.Spell.CurDot()
+
+		fa.CastSpell = fa.RegisterSpell(SpellConfig{
+			ActionID:    ActionID{SpellID: 43},
+			SpellSchool: SpellSchoolShadow,
+			ProcMask:    ProcMaskSpellDamage,
+			Cast: CastConfig{
+				DefaultCast: Cast{
+					GCD:      GCDDefault,
+					CastTime: time.Second * 2,
+				},
+				CD: Cooldown{
+					Timer:    fa.NewTimer(),
+					Duration: time.Second * 30,
+				},
+			},
+			DamageMultiplier: 1,
+			ThreatMultiplier: 1,
+
+			ApplyEffects: func(sim *Simulation, target *Unit, spell *Spell) {
+				spell.CalcAndDealDamage(sim, target, 100, spell.OutcomeMagicHit)
+			},
+		})
 	}
 
 	return fa
 }
 
-func SetupFakeSim() *Simulation {
-	sim := NewSim(&proto.RaidSimRequest{
+// Returns the request of SetupFakeSim, for tests which need to change it before creating their sim.
+func fakeSimRequest() *proto.RaidSimRequest {
+	return &proto.RaidSimRequest{
 		SimOptions: &proto.SimOptions{
 			RandomSeed: 100,
 		},
@@ -121,12 +146,54 @@ func SetupFakeSim() *Simulation {
 			},
 			Duration: 180,
 		},
-	}, simsignals.CreateSignals())
+	}
+}
+
+// Returns a rotation for the fake agent, which keeps its dot up.
+func fakeDotRotation() *proto.APLRotation {
+	fakeDot := &proto.ActionID{RawId: &proto.ActionID_SpellId{SpellId: 42}}
+	return &proto.APLRotation{
+		Type: proto.APLRotation_TypeAPL,
+		PriorityList: []*proto.APLListItem{
+			{Action: &proto.APLAction{
+				Condition: &proto.APLValue{Value: &proto.APLValue_Not{Not: &proto.APLValueNot{
+					Val: &proto.APLValue{Value: &proto.APLValue_DotIsActive{DotIsActive: &proto.APLValueDotIsActive{SpellId: fakeDot}}},
+				}}},
+				Action: &proto.APLAction_CastSpell{CastSpell: &proto.APLActionCastSpell{SpellId: fakeDot}},
+			}},
+		},
+	}
+}
+
+func SetupFakeSim() *Simulation {
+	sim := NewSim(fakeSimRequest(), simsignals.CreateSignals())
 	sim.Reset()
 
 	return sim
 }
 
+// Starts a new iteration of the sim and runs it up to the given time, so tests can check the state of
+// units during the fight. Use runFakeSimUntil to continue the iteration, and finish it with
+// sim.runPendingActions() and sim.Cleanup() before starting another one.
+func startFakeSimUntil(sim *Simulation, until time.Duration) {
+	sim.Reset()
+	sim.PrePull()
+	runFakeSimUntil(sim, until)
+}
+
+func runFakeSimUntil(sim *Simulation, until time.Duration) {
+	reached := false
+	sim.AddPendingAction(&PendingAction{
+		NextActionAt: until,
+		Priority:     ActionPriorityLow,
+		OnAction: func(_ *Simulation) {
+			reached = true
+		},
+	})
+	for !reached && !sim.Step() {
+	}
+}
+
 func expectDotTickDamage(t *testing.T, sim *Simulation, dot *Dot, expectedDamage float64) {
 	damageBefore := dot.Spell.SpellMetrics[0].TotalDamage
 	dot.TickOnce(sim)
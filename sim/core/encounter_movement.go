@@ -0,0 +1,113 @@
+package core
+
+import (
+	"math"
+	"time"
+
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+// EncounterMovement is movement forced on the whole raid, e.g. Thaddius polarity shifts.
+// Players either move Distance yards in place, or leave to OutOfRangeDistance from their
+// target and come back after OutOfRangeDuration.
+type EncounterMovement struct {
+	StartTime time.Duration
+	Interval  time.Duration
+	Jitter    time.Duration
+
+	Distance float64
+
+	OutOfRangeDistance float64
+	OutOfRangeDuration time.Duration
+
+	MeleeOnly bool
+}
+
+func newEncounterMovement(config *proto.EncounterMovement) *EncounterMovement {
+	return &EncounterMovement{
+		StartTime:          DurationFromSeconds(max(config.StartTime, 0)),
+		Interval:           DurationFromSeconds(max(config.Interval, 0)),
+		Jitter:             DurationFromSeconds(max(config.Jitter, 0)),
+		Distance:           math.Round(max(config.Distance, 0)),
+		OutOfRangeDistance: math.Round(max(config.OutOfRangeDistance, 0)),
+		OutOfRangeDuration: DurationFromSeconds(max(config.OutOfRangeDuration, 0)),
+		MeleeOnly:          config.MeleeOnly,
+	}
+}
+
+func (movement *EncounterMovement) leavesRange() bool {
+	return movement.OutOfRangeDistance > 0 && movement.OutOfRangeDuration > 0
+}
+
+// Returns the time of the movement scheduled at nominalTime, with this iteration's jitter applied.
+func (movement *EncounterMovement) jitteredTime(sim *Simulation, nominalTime time.Duration) time.Duration {
+	if movement.Jitter == 0 {
+		return nominalTime
+	}
+	jitter := time.Duration((sim.RandomFloat("Encounter Movement")*2 - 1) * float64(movement.Jitter))
+	return max(nominalTime+jitter, sim.CurrentTime)
+}
+
+func (movement *EncounterMovement) schedule(sim *Simulation, nominalTime time.Duration) {
+	StartDelayedAction(sim, DelayedActionOptions{
+		DoAt:     movement.jitteredTime(sim, nominalTime),
+		Priority: ActionPriorityDOT,
+		OnAction: func(sim *Simulation) {
+			movement.start(sim)
+			if movement.Interval > 0 {
+				movement.schedule(sim, nominalTime+movement.Interval)
+			}
+		},
+	})
+}
+
+func (movement *EncounterMovement) start(sim *Simulation) {
+	for _, unit := range sim.Raid.AllPlayerUnits {
+		if !unit.IsEnabled() || (movement.MeleeOnly && unit.DistanceFromTarget > MaxMeleeAttackRange) {
+			continue
+		}
+
+		if !movement.leavesRange() {
+			unit.MoveDistance(movement.Distance, sim)
+			continue
+		}
+
+		if unit.DistanceFromTarget >= movement.OutOfRangeDistance {
+			continue
+		}
+
+		unit := unit
+		returnTo := unit.DistanceFromTarget
+		unit.MoveTo(movement.OutOfRangeDistance, sim)
+
+		// Players can't come back before they got out.
+		travelTime := unit.MovementHandler.MoveDuration(movement.OutOfRangeDistance - returnTo)
+		scheduleReturn(sim, unit, returnTo, sim.CurrentTime+max(movement.OutOfRangeDuration, travelTime))
+	}
+}
+
+// MoveTo ignores units which are still moving, so the return is retried until the unit has stopped.
+func scheduleReturn(sim *Simulation, unit *Unit, returnTo float64, doAt time.Duration) {
+	StartDelayedAction(sim, DelayedActionOptions{
+		DoAt: doAt,
+		OnAction: func(sim *Simulation) {
+			if unit.IsMoving() {
+				scheduleReturn(sim, unit, returnTo, sim.CurrentTime+unit.MovementHandler.yardDuration())
+				return
+			}
+			unit.MoveTo(returnTo, sim)
+		},
+	})
+}
+
+// Adds raid-wide movement to the encounter, for AIs with their own movement patterns.
+func (encounter *Encounter) AddMovement(movement *EncounterMovement) *EncounterMovement {
+	encounter.Movements = append(encounter.Movements, movement)
+	return movement
+}
+
+func (sim *Simulation) scheduleEncounterMovements() {
+	for _, movement := range sim.Encounter.Movements {
+		movement.schedule(sim, movement.StartTime)
+	}
+}
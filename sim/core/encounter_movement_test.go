@@ -0,0 +1,84 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wowsims/sod/sim/core/proto"
+	"github.com/wowsims/sod/sim/core/simsignals"
+)
+
+func TestEncounterMovementLeavesRange(t *testing.T) {
+	rsr := fakeSimRequest()
+	rsr.Raid.Parties[0].Players[0].DistanceFromTarget = 5
+	rsr.Encounter.Movements = []*proto.EncounterMovement{{
+		StartTime:          10,
+		Interval:           30,
+		Jitter:             2,
+		OutOfRangeDistance: 40,
+		OutOfRangeDuration: 8,
+	}}
+	sim := NewSim(rsr, simsignals.CreateSignals())
+	player := sim.Raid.Parties[0].Players[0].GetCharacter()
+
+	// Checks at the middle and the end of the first movement.
+	startFakeSimUntil(sim, time.Second*16)
+	if player.DistanceFromTarget <= 5 {
+		t.Errorf("Expected the player to have left range at %s, still at %0.1f yards", sim.CurrentTime, player.DistanceFromTarget)
+	}
+	runFakeSimUntil(sim, time.Second*30)
+	if player.DistanceFromTarget != 5 || player.IsMoving() {
+		t.Errorf("Expected the player to be back at 5 yards at %s, is at %0.1f yards", sim.CurrentTime, player.DistanceFromTarget)
+	}
+}
+
+func TestEncounterMovementReturnsAfterMoving(t *testing.T) {
+	rsr := fakeSimRequest()
+	rsr.Raid.Parties[0].Players[0].DistanceFromTarget = 5
+	rsr.Encounter.Movements = []*proto.EncounterMovement{
+		{StartTime: 10, OutOfRangeDistance: 39.6, OutOfRangeDuration: 8},
+		// Still moving in place when the first movement wants to come back.
+		{StartTime: 17, Distance: 30},
+	}
+	sim := NewSim(rsr, simsignals.CreateSignals())
+	player := sim.Raid.Parties[0].Players[0].GetCharacter()
+
+	startFakeSimUntil(sim, time.Second*18)
+	if player.DistanceFromTarget != 40 || !player.IsMoving() {
+		t.Fatalf("Expected the player to be moving at 40 yards at %s, is at %0.1f yards, moving: %t", sim.CurrentTime, player.DistanceFromTarget, player.IsMoving())
+	}
+	runFakeSimUntil(sim, time.Second*30)
+	if player.DistanceFromTarget != 5 || player.IsMoving() {
+		t.Errorf("Expected the player to be back at 5 yards at %s, is at %0.1f yards", sim.CurrentTime, player.DistanceFromTarget)
+	}
+}
+
+func TestEncounterMovementInterruptsCasts(t *testing.T) {
+	sim := SetupFakeSim()
+	spell := sim.Raid.Parties[0].Players[0].(*FakeAgent).CastSpell
+	character := spell.Unit
+
+	if !spell.Cast(sim, sim.GetTargetUnit(0)) || !character.IsCasting(sim) {
+		t.Fatalf("Expected the spell to start casting")
+	}
+
+	movement := &EncounterMovement{StartTime: sim.CurrentTime, Distance: 10}
+	movement.start(sim)
+	if !character.IsMoving() || character.IsCasting(sim) {
+		t.Fatalf("Expected movement to interrupt the cast, moving: %t, casting: %t", character.IsMoving(), character.IsCasting(sim))
+	}
+	if !spell.CD.IsReady(sim) {
+		t.Errorf("Expected the interrupted spell to be off cooldown, ready in %s", spell.CD.TimeToReady(sim))
+	}
+	if expected := sim.CurrentTime + GCDDefault; character.GCD.ReadyAt() != expected {
+		t.Errorf("Expected the GCD started by the cast to be kept until %s, ready at %s", expected, character.GCD.ReadyAt())
+	}
+
+	sim.runPendingActions()
+	if casts := spell.SpellMetrics[0].Casts; casts != 0 {
+		t.Errorf("Interrupted cast should not complete, got %d casts", casts)
+	}
+	if character.IsMoving() {
+		t.Errorf("Expected the movement to be over by the end of the fight")
+	}
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"math"
+	"time"
+
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+// EncounterPhase is a named section of an encounter, e.g. a boss transition or a soft enrage.
+// It starts once the targets drop to HealthProportion of their health or, if that is not set,
+// at StartTime. Phases never end, the current phase is just the one which started last.
+type EncounterPhase struct {
+	Name             string
+	HealthProportion float64
+	StartTime        time.Duration
+
+	onStart []func(sim *Simulation, phase *EncounterPhase)
+
+	// Reset at the start of each iteration.
+	started bool
+}
+
+func newEncounterPhase(config *proto.EncounterPhase) *EncounterPhase {
+	return &EncounterPhase{
+		Name:             config.Name,
+		HealthProportion: min(max(config.GetHealthProportion(), 0), 1),
+		StartTime:        DurationFromSeconds(config.GetTimeSeconds()),
+	}
+}
+
+// Adds a phase to the encounter, for AIs with their own transitions.
+func (encounter *Encounter) AddPhase(phase *EncounterPhase) *EncounterPhase {
+	encounter.Phases = append(encounter.Phases, phase)
+	return phase
+}
+
+// Returns the phase with the given name, or nil if the encounter has none.
+func (encounter *Encounter) GetPhase(name string) *EncounterPhase {
+	for _, phase := range encounter.Phases {
+		if phase.Name == name {
+			return phase
+		}
+	}
+	return nil
+}
+
+// Registers a callback which is invoked each iteration when this phase starts.
+func (phase *EncounterPhase) OnStart(callback func(sim *Simulation, phase *EncounterPhase)) {
+	phase.onStart = append(phase.onStart, callback)
+}
+
+func (phase *EncounterPhase) IsStarted() bool {
+	return phase.started
+}
+
+// Returns the damage taken by the encounter at which this phase starts, for health based fights.
+func (phase *EncounterPhase) startDamage(sim *Simulation) float64 {
+	if phase.HealthProportion == 0 || sim.Encounter.EndFightAtHealth == 0 {
+		return math.MaxFloat64
+	}
+	return (1 - phase.HealthProportion) * sim.Encounter.EndFightAtHealth
+}
+
+// Returns the time at which this phase starts. For health based phases in health based
+// fights, this is never known in advance.
+func (phase *EncounterPhase) startTime(sim *Simulation) time.Duration {
+	if phase.HealthProportion == 0 {
+		return phase.StartTime
+	}
+	if sim.Encounter.EndFightAtHealth > 0 {
+		return NeverExpires
+	}
+	return time.Duration((1 - phase.HealthProportion) * float64(sim.Duration))
+}
+
+func (phase *EncounterPhase) reached(sim *Simulation) bool {
+	return sim.CurrentTime >= phase.startTime(sim) || sim.Encounter.DamageTaken >= phase.startDamage(sim)
+}
+
+// Estimates the time until this phase starts, extrapolating the damage taken so far for
+// health based phases in health based fights.
+func (phase *EncounterPhase) timeToStart(sim *Simulation) time.Duration {
+	if startTime := phase.startTime(sim); startTime != NeverExpires {
+		return max(0, startTime-sim.CurrentTime)
+	}
+	if sim.CurrentTime < time.Second*5 || sim.Encounter.DamageTaken == 0 {
+		return max(0, time.Duration((1-phase.HealthProportion)*float64(sim.Duration))-sim.CurrentTime)
+	}
+	dps := sim.Encounter.DamageTaken / sim.CurrentTime.Seconds()
+	return max(0, DurationFromSeconds((phase.startDamage(sim)-sim.Encounter.DamageTaken)/dps))
+}
+
+func (sim *Simulation) resetEncounterPhases() {
+	sim.currentPhase = nil
+	for _, phase := range sim.Encounter.Phases {
+		phase.started = false
+	}
+	sim.updateNextPhase()
+}
+
+// updateNextPhase updates nextPhaseDuration and nextPhaseDamage to the earliest phase which hasn't started yet.
+func (sim *Simulation) updateNextPhase() {
+	sim.nextPhaseDuration = NeverExpires
+	sim.nextPhaseDamage = math.MaxFloat64
+	for _, phase := range sim.Encounter.Phases {
+		if !phase.started {
+			sim.nextPhaseDuration = min(sim.nextPhaseDuration, phase.startTime(sim))
+			sim.nextPhaseDamage = min(sim.nextPhaseDamage, phase.startDamage(sim))
+		}
+	}
+}
+
+// Starts all phases which have been reached, in the order they are declared.
+func (sim *Simulation) startEncounterPhases() {
+	for _, phase := range sim.Encounter.Phases {
+		if phase.started || !phase.reached(sim) {
+			continue
+		}
+
+		phase.started = true
+		sim.currentPhase = phase
+		if sim.Log != nil {
+			sim.Log("Encounter phase %s started", phase.Name)
+		}
+		for _, callback := range phase.onStart {
+			callback(sim, phase)
+		}
+	}
+	sim.updateNextPhase()
+}
+
+// Returns the phase which started last, or nil before the first one.
+func (sim *Simulation) CurrentEncounterPhase() *EncounterPhase {
+	return sim.currentPhase
+}
+
+// Returns the time until the next phase starts, or the remaining duration if there is none.
+func (sim *Simulation) TimeToNextEncounterPhase() time.Duration {
+	timeToNext := sim.GetRemainingDuration()
+	for _, phase := range sim.Encounter.Phases {
+		if !phase.started {
+			timeToNext = min(timeToNext, phase.timeToStart(sim))
+		}
+	}
+	return timeToNext
+}
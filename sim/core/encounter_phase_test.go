@@ -0,0 +1,87 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wowsims/sod/sim/core/proto"
+	"github.com/wowsims/sod/sim/core/simsignals"
+	"github.com/wowsims/sod/sim/core/stats"
+)
+
+func TestEncounterPhases(t *testing.T) {
+	rsr := replayTestRequest(false)
+	rsr.Encounter.DurationVariation = 0
+	rsr.Encounter.Phases = []*proto.EncounterPhase{
+		{Name: "Transition", Trigger: &proto.EncounterPhase_HealthProportion{HealthProportion: 0.5}},
+		{Name: "Enrage", Trigger: &proto.EncounterPhase_TimeSeconds{TimeSeconds: 45}},
+	}
+	sim := NewSim(rsr, simsignals.CreateSignals())
+
+	currentPhase := &APLValueCurrentPhase{}
+	timeToNextPhase := &APLValueTimeToNextPhase{}
+	started := map[string][]time.Duration{}
+	for _, phase := range sim.Encounter.Phases {
+		phase.OnStart(func(sim *Simulation, phase *EncounterPhase) {
+			started[phase.Name] = append(started[phase.Name], sim.CurrentTime)
+			if name := currentPhase.GetString(sim); name != phase.Name {
+				t.Errorf("Expected current phase %s, got %q", phase.Name, name)
+			}
+		})
+	}
+
+	sim.Encounter.GetPhase("Transition").OnStart(func(sim *Simulation, _ *EncounterPhase) {
+		if remaining := timeToNextPhase.GetDuration(sim); remaining != time.Second*45-sim.CurrentTime {
+			t.Errorf("Expected %s until the enrage, got %s", time.Second*45-sim.CurrentTime, remaining)
+		}
+	})
+	sim.Encounter.GetPhase("Enrage").OnStart(func(sim *Simulation, _ *EncounterPhase) {
+		if remaining := timeToNextPhase.GetDuration(sim); remaining != sim.GetRemainingDuration() {
+			t.Errorf("Expected the remaining duration after the last phase, got %s", remaining)
+		}
+	})
+
+	result := sim.run()
+	if result.Error != nil {
+		t.Fatalf("Sim failed: %s", result.Error.Message)
+	}
+
+	for name, startTime := range map[string]time.Duration{"Transition": time.Second * 30, "Enrage": time.Second * 45} {
+		if len(started[name]) != int(rsr.SimOptions.Iterations) {
+			t.Fatalf("Expected %s to start once per iteration, started %d times", name, len(started[name]))
+		}
+		for _, actual := range started[name] {
+			if actual < startTime || actual > startTime+time.Second*5 {
+				t.Errorf("Expected %s to start at %s, started at %s", name, startTime, actual)
+			}
+		}
+	}
+}
+
+func TestEncounterPhasesHealthFight(t *testing.T) {
+	rsr := replayTestRequest(false)
+	rsr.Encounter.UseHealth = true
+	targetStats := stats.Stats{}
+	targetStats[stats.Health] = 50000
+	rsr.Encounter.Targets[0].Stats = targetStats[:]
+	rsr.Encounter.Phases = []*proto.EncounterPhase{
+		{Name: "Transition", Trigger: &proto.EncounterPhase_HealthProportion{HealthProportion: 0.3}},
+	}
+	sim := NewSim(rsr, simsignals.CreateSignals())
+
+	iterations := 0
+	sim.Encounter.GetPhase("Transition").OnStart(func(sim *Simulation, _ *EncounterPhase) {
+		iterations++
+		if sim.Encounter.DamageTaken < 35000 {
+			t.Errorf("Expected the transition at 30%% health, started after %0.0f damage", sim.Encounter.DamageTaken)
+		}
+	})
+
+	result := sim.run()
+	if result.Error != nil {
+		t.Fatalf("Sim failed: %s", result.Error.Message)
+	}
+	if iterations != int(rsr.SimOptions.Iterations) {
+		t.Errorf("Expected the transition once per iteration, started %d times", iterations)
+	}
+}
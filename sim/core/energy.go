@@ -169,7 +169,7 @@ func (eb *energyBar) addEnergyInternal(sim *Simulation, amount float64, metrics
 	metrics.AddEvent(amount, newEnergy-eb.currentEnergy)
 
 	if sim.Log != nil {
-		eb.unit.Log(sim, "Gained %0.3f energy from %s (%0.3f --> %0.3f).", amount, metrics.ActionID, eb.currentEnergy, newEnergy)
+		eb.unit.logResourceChange(sim, proto.ResourceType_ResourceTypeEnergy, metrics.ActionID, false, amount, eb.currentEnergy, newEnergy)
 	}
 
 	crossedThreshold := eb.cumulativeEnergyDecisionThresholds == nil || eb.cumulativeEnergyDecisionThresholds[int(eb.currentEnergy)] != eb.cumulativeEnergyDecisionThresholds[int(newEnergy)]
@@ -191,7 +191,7 @@ func (eb *energyBar) SpendEnergy(sim *Simulation, amount float64, metrics *Resou
 	metrics.AddEvent(-amount, -amount)
 
 	if sim.Log != nil {
-		eb.unit.Log(sim, "Spent %0.3f energy from %s (%0.3f --> %0.3f).", amount, metrics.ActionID, eb.currentEnergy, newEnergy)
+		eb.unit.logResourceChange(sim, proto.ResourceType_ResourceTypeEnergy, metrics.ActionID, true, amount, eb.currentEnergy, newEnergy)
 	}
 
 	eb.currentEnergy = newEnergy
@@ -218,7 +218,7 @@ func (eb *energyBar) AddComboPointsIgnoreTarget(sim *Simulation, pointsToAdd int
 	metrics.AddEvent(float64(pointsToAdd), float64(newComboPoints-eb.comboPoints))
 
 	if sim.Log != nil {
-		eb.unit.Log(sim, "Gained %d combo points on %s from %s (%d --> %d)", pointsToAdd, eb.comboPointTarget.LogLabel(), metrics.ActionID, eb.comboPoints, newComboPoints)
+		eb.unit.logResourceChangeOn(sim, eb.comboPointTarget, proto.ResourceType_ResourceTypeComboPoints, metrics.ActionID, false, float64(pointsToAdd), float64(eb.comboPoints), float64(newComboPoints))
 	}
 
 	eb.comboPoints = newComboPoints
@@ -246,15 +246,15 @@ func (eb *energyBar) AddComboPoints(sim *Simulation, pointsToAdd int32, target *
 
 		if sim.Log != nil {
 			// TODO there should probably be some separate combo point metric to capture loss
-			eb.unit.Log(sim, "Spent %d combo points on %s from %s (%d --> %d) (target swap)", pointsToAdd, eb.comboPointTarget.LogLabel(), metrics.ActionID, eb.comboPoints, 0)
-			eb.unit.Log(sim, "Gained %d combo points on %s from %s (%d --> %d)", pointsToAdd, target.LogLabel(), metrics.ActionID, 0, newComboPoints)
+			eb.unit.logResourceChangeOn(sim, eb.comboPointTarget, proto.ResourceType_ResourceTypeComboPoints, metrics.ActionID, true, float64(pointsToAdd), float64(eb.comboPoints), 0)
+			eb.unit.logResourceChangeOn(sim, target, proto.ResourceType_ResourceTypeComboPoints, metrics.ActionID, false, float64(pointsToAdd), 0, float64(newComboPoints))
 		}
 	} else {
 		newComboPoints = min(eb.comboPoints+pointsToAdd, 5)
 		metrics.AddEvent(float64(pointsToAdd), float64(newComboPoints-eb.comboPoints))
 
 		if sim.Log != nil {
-			eb.unit.Log(sim, "Gained %d combo points on %s from %s (%d --> %d)", pointsToAdd, target.LogLabel(), metrics.ActionID, eb.comboPoints, newComboPoints)
+			eb.unit.logResourceChangeOn(sim, target, proto.ResourceType_ResourceTypeComboPoints, metrics.ActionID, false, float64(pointsToAdd), float64(eb.comboPoints), float64(newComboPoints))
 		}
 	}
 
@@ -280,7 +280,7 @@ func (eb *energyBar) SpendComboPoints(sim *Simulation, spell *Spell) {
 	comboPoints := eb.comboPoints
 
 	if sim.Log != nil {
-		eb.unit.Log(sim, "Spent %d combo points from %s (%d --> %d).", comboPoints, spell.ActionID, comboPoints, 0)
+		eb.unit.logResourceChange(sim, proto.ResourceType_ResourceTypeComboPoints, spell.ActionID, true, float64(comboPoints), float64(comboPoints), 0)
 	}
 	spell.ComboPointMetrics().AddEvent(float64(-comboPoints), float64(-comboPoints))
 	eb.comboPoints = 0
@@ -89,7 +89,7 @@ func (fb *focusBar) AddFocus(sim *Simulation, amount float64, metrics *ResourceM
 	metrics.AddEvent(amount, newFocus-fb.currentFocus)
 
 	if sim.Log != nil {
-		fb.unit.Log(sim, "Gained %0.3f focus from %s (%0.3f --> %0.3f).", amount, metrics.ActionID, fb.currentFocus, newFocus)
+		fb.unit.logResourceChange(sim, proto.ResourceType_ResourceTypeFocus, metrics.ActionID, false, amount, fb.currentFocus, newFocus)
 	}
 
 	fb.currentFocus = newFocus
@@ -108,7 +108,7 @@ func (fb *focusBar) SpendFocus(sim *Simulation, amount float64, metrics *Resourc
 	metrics.AddEvent(-amount, -amount)
 
 	if sim.Log != nil {
-		fb.unit.Log(sim, "Spent %0.3f focus from %s (%0.3f --> %0.3f).", amount, metrics.ActionID, fb.currentFocus, newFocus)
+		fb.unit.logResourceChange(sim, proto.ResourceType_ResourceTypeFocus, metrics.ActionID, true, amount, fb.currentFocus, newFocus)
 	}
 
 	fb.currentFocus = newFocus
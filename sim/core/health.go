@@ -55,7 +55,7 @@ func (hb *healthBar) GainHealth(sim *Simulation, amount float64, metrics *Resour
 	metrics.AddEvent(amount, newHealth-oldHealth)
 
 	if sim.Log != nil {
-		hb.unit.Log(sim, "Gained %0.3f health from %s (%0.3f --> %0.3f).", amount, metrics.ActionID, oldHealth, newHealth)
+		hb.unit.logResourceChange(sim, proto.ResourceType_ResourceTypeHealth, metrics.ActionID, false, amount, oldHealth, newHealth)
 	}
 
 	hb.currentHealth = newHealth
@@ -80,7 +80,7 @@ func (hb *healthBar) RemoveHealth(sim *Simulation, amount float64, metrics *Reso
 	}
 
 	if sim.Log != nil {
-		hb.unit.Log(sim, "Spent %0.3f health from %s (%0.3f --> %0.3f).", amount, metrics.ActionID, oldHealth, newHealth)
+		hb.unit.logResourceChange(sim, proto.ResourceType_ResourceTypeHealth, metrics.ActionID, true, amount, oldHealth, newHealth)
 	}
 
 	hb.currentHealth = newHealth
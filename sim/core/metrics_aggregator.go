@@ -23,7 +23,10 @@ type DistributionMetrics struct {
 	maxSeed int64
 	minSeed int64
 	hist    map[int32]int32 // rounded DPS to count
+	sketch  quantileSketch
 	sample  []float64
+
+	saveSketch bool
 }
 
 func (distMetrics *DistributionMetrics) reset() {
@@ -34,6 +37,8 @@ func (distMetrics *DistributionMetrics) reset() {
 func (distMetrics *DistributionMetrics) doneIteration(sim *Simulation) {
 	dps := distMetrics.Total / sim.Duration.Seconds()
 	distMetrics.add(dps)
+	distMetrics.sketch.add(dps)
+	distMetrics.saveSketch = sim.Options.SaveQuantileSketch
 
 	if sim.Options.SaveAllValues {
 		if cap(distMetrics.sample) < int(sim.Options.Iterations) {
@@ -58,7 +63,7 @@ func (distMetrics *DistributionMetrics) doneIteration(sim *Simulation) {
 func (distMetrics *DistributionMetrics) ToProto() *proto.DistributionMetrics {
 	mean, stdev := distMetrics.meanAndStdDev()
 
-	return &proto.DistributionMetrics{
+	metrics := &proto.DistributionMetrics{
 		Avg:       mean,
 		Stdev:     stdev,
 		Max:       distMetrics.max,
@@ -73,12 +78,18 @@ func (distMetrics *DistributionMetrics) ToProto() *proto.DistributionMetrics {
 			SumSq: distMetrics.sumSq,
 		},
 	}
+	if distMetrics.saveSketch {
+		metrics.Sketch = distMetrics.sketch.ToProto()
+	}
+	setDistributionPercentiles(metrics, &distMetrics.sketch)
+	return metrics
 }
 
 func NewDistributionMetrics() DistributionMetrics {
 	return DistributionMetrics{
-		hist: make(map[int32]int32),
-		min:  -1,
+		hist:   make(map[int32]int32),
+		sketch: newQuantileSketch(),
+		min:    -1,
 	}
 }
 
@@ -95,13 +106,19 @@ type UnitMetrics struct {
 	isTanking bool
 	tmiBin    int32
 
+	timeline *TimelineMetrics // Only set if SimOptions.TimelineBucketSeconds is.
+	apl      *APLMetrics      // Only set if SimOptions.AplMetrics is, for units with a rotation.
+
 	CharacterIterationMetrics
 
 	// Aggregate values. These are updated after each iteration.
-	numItersDead int32
-	oomTimeSum   float64
-	actions      map[ActionID]*ActionMetrics
-	resources    []*ResourceMetrics
+	numItersDead   int32
+	oomTimeSum     float64
+	presentTimeSum float64
+	aggroGainedSum int32
+	aggroTimeSum   float64
+	actions        map[ActionID]*ActionMetrics
+	resources      []*ResourceMetrics
 }
 
 // Metrics for the current iteration, for 1 agent. Keep this as a separate
@@ -115,6 +132,11 @@ type CharacterIterationMetrics struct {
 
 	OOMTime time.Duration // time spent not casting and waiting for regen.
 
+	PresentTime time.Duration // time a target was spawned for, not tracked for players.
+
+	AggroGained int32         // times a player pulled aggro from targets which switch on threat.
+	AggroTime   time.Duration // time a player held aggro, summed over targets.
+
 	FirstOOMTimestamp time.Duration // Timestamp at which unit first went OOM.
 }
 
@@ -440,6 +462,9 @@ func (unitMetrics *UnitMetrics) addSpellMetrics(spell *Spell, actionID ActionID,
 // Assumes that doneIteration() has already been called on the pet metrics.
 func (unitMetrics *UnitMetrics) AddFinalPetMetrics(petMetrics *UnitMetrics) {
 	unitMetrics.dps.Total += petMetrics.dps.Total
+	if unitMetrics.timeline != nil && petMetrics.timeline != nil {
+		unitMetrics.timeline.addPetDamage(petMetrics.timeline)
+	}
 }
 
 func (unitMetrics *UnitMetrics) AddOOMTime(sim *Simulation, dur time.Duration) {
@@ -470,6 +495,12 @@ func (unitMetrics *UnitMetrics) reset() {
 	unitMetrics.hps.reset()
 	unitMetrics.tto.reset()
 	unitMetrics.CharacterIterationMetrics = CharacterIterationMetrics{}
+	if unitMetrics.timeline != nil {
+		unitMetrics.timeline.reset()
+	}
+	if unitMetrics.apl != nil {
+		unitMetrics.apl.reset()
+	}
 
 	for _, resourceMetrics := range unitMetrics.resources {
 		resourceMetrics.reset()
@@ -513,8 +544,17 @@ func (unitMetrics *UnitMetrics) doneIteration(unit *Unit, sim *Simulation) {
 	unitMetrics.tmi.doneIteration(sim)
 	unitMetrics.hps.doneIteration(sim)
 	unitMetrics.tto.doneIteration(sim)
+	if unitMetrics.timeline != nil {
+		unitMetrics.timeline.doneIteration(sim)
+	}
+	if unitMetrics.apl != nil {
+		unitMetrics.apl.doneIteration()
+	}
 
 	unitMetrics.oomTimeSum += unitMetrics.OOMTime.Seconds()
+	unitMetrics.presentTimeSum += unitMetrics.PresentTime.Seconds()
+	unitMetrics.aggroGainedSum += unitMetrics.AggroGained
+	unitMetrics.aggroTimeSum += unitMetrics.AggroTime.Seconds()
 	if unitMetrics.Died {
 		unitMetrics.numItersDead++
 	}
@@ -595,15 +635,25 @@ func (unitMetrics *UnitMetrics) calculateTMI(unit *Unit, sim *Simulation) float6
 func (unitMetrics *UnitMetrics) ToProto() *proto.UnitMetrics {
 	n := float64(unitMetrics.dps.n)
 	protoMetrics := &proto.UnitMetrics{
-		Dps:           unitMetrics.dps.ToProto(),
-		Dpasp:         unitMetrics.dpasp.ToProto(),
-		Threat:        unitMetrics.threat.ToProto(),
-		Dtps:          unitMetrics.dtps.ToProto(),
-		Tmi:           unitMetrics.tmi.ToProto(),
-		Hps:           unitMetrics.hps.ToProto(),
-		Tto:           unitMetrics.tto.ToProto(),
-		SecondsOomAvg: unitMetrics.oomTimeSum / n,
-		ChanceOfDeath: float64(unitMetrics.numItersDead) / n,
+		Dps:                 unitMetrics.dps.ToProto(),
+		Dpasp:               unitMetrics.dpasp.ToProto(),
+		Threat:              unitMetrics.threat.ToProto(),
+		Dtps:                unitMetrics.dtps.ToProto(),
+		Tmi:                 unitMetrics.tmi.ToProto(),
+		Hps:                 unitMetrics.hps.ToProto(),
+		Tto:                 unitMetrics.tto.ToProto(),
+		SecondsOomAvg:       unitMetrics.oomTimeSum / n,
+		ChanceOfDeath:       float64(unitMetrics.numItersDead) / n,
+		SecondsPresentAvg:   unitMetrics.presentTimeSum / n,
+		AggroGainedAvg:      float64(unitMetrics.aggroGainedSum) / n,
+		SecondsWithAggroAvg: unitMetrics.aggroTimeSum / n,
+	}
+
+	if unitMetrics.timeline != nil {
+		protoMetrics.Timeline = unitMetrics.timeline.ToProto()
+	}
+	if unitMetrics.apl != nil {
+		protoMetrics.AplMetrics = unitMetrics.apl.ToProto(n)
 	}
 
 	protoMetrics.Actions = make([]*proto.ActionMetrics, 0, len(unitMetrics.actions))
@@ -0,0 +1,318 @@
+package core
+
+import (
+	"slices"
+	"time"
+
+	"github.com/wowsims/sod/sim/core/proto"
+	googleProto "google.golang.org/protobuf/proto"
+)
+
+// Resources sampled by TimelineMetrics, for units which have the corresponding bar.
+var timelineResourceTypes = []proto.ResourceType{
+	proto.ResourceType_ResourceTypeMana,
+	proto.ResourceType_ResourceTypeEnergy,
+	proto.ResourceType_ResourceTypeRage,
+}
+
+// TimelineMetrics tracks damage, resource levels and aura uptimes of a unit in
+// fixed width time buckets, averaged across iterations. Only created when
+// SimOptions.TimelineBucketSeconds is set.
+type TimelineMetrics struct {
+	bucketWidth time.Duration
+
+	auraIDs     []ActionID
+	auraIndices map[ActionID]int
+
+	// Values for the current iteration. These are cleared after each iteration.
+	damage    []float64
+	resources [][]float64       // Indexed like timelineResourceTypes, nil if the unit doesn't have that resource.
+	uptime    [][]time.Duration // Indexed like auraIDs.
+
+	// Aggregate values. These are updated after each iteration.
+	iterations   []int32
+	seconds      []float64
+	damageSum    []float64
+	resourceSums [][]float64
+	uptimeSums   [][]float64
+}
+
+func newTimelineMetrics(bucketWidth time.Duration, auraIDs []ActionID) *TimelineMetrics {
+	timeline := &TimelineMetrics{
+		bucketWidth:  bucketWidth,
+		auraIDs:      auraIDs,
+		auraIndices:  make(map[ActionID]int, len(auraIDs)),
+		resources:    make([][]float64, len(timelineResourceTypes)),
+		uptime:       make([][]time.Duration, len(auraIDs)),
+		resourceSums: make([][]float64, len(timelineResourceTypes)),
+		uptimeSums:   make([][]float64, len(auraIDs)),
+	}
+	for i, auraID := range auraIDs {
+		timeline.auraIndices[auraID] = i
+	}
+	return timeline
+}
+
+// Grows values to at least n elements, filling with zeroes.
+func extendTimeline[T any](values []T, n int) []T {
+	if len(values) < n {
+		values = append(values, make([]T, n-len(values))...)
+	}
+	return values
+}
+
+// Returns the bucket containing t. Anything at or past the end of the iteration goes into the last bucket.
+func (timeline *TimelineMetrics) bucket(sim *Simulation, t time.Duration) int {
+	return int(max(0, min(t, sim.Duration-1)) / timeline.bucketWidth)
+}
+
+func (timeline *TimelineMetrics) numBuckets(sim *Simulation) int {
+	return int((sim.Duration + timeline.bucketWidth - 1) / timeline.bucketWidth)
+}
+
+func (timeline *TimelineMetrics) reset() {
+	clear(timeline.damage)
+	for _, values := range timeline.resources {
+		clear(values)
+	}
+	for _, values := range timeline.uptime {
+		clear(values)
+	}
+}
+
+func (timeline *TimelineMetrics) addDamage(sim *Simulation, damage float64) {
+	bucket := timeline.bucket(sim, sim.CurrentTime)
+	timeline.damage = extendTimeline(timeline.damage, bucket+1)
+	timeline.damage[bucket] += damage
+}
+
+func (timeline *TimelineMetrics) addPetDamage(petTimeline *TimelineMetrics) {
+	timeline.damage = extendTimeline(timeline.damage, len(petTimeline.damage))
+	for bucket, damage := range petTimeline.damage {
+		timeline.damage[bucket] += damage
+	}
+}
+
+// Splits the interval an aura was active, [start, end), across buckets.
+func (timeline *TimelineMetrics) addAuraUptime(sim *Simulation, auraID ActionID, start time.Duration, end time.Duration) {
+	i, ok := timeline.auraIndices[auraID]
+	if !ok || end <= start {
+		return
+	}
+
+	lastBucket := timeline.bucket(sim, end)
+	timeline.uptime[i] = extendTimeline(timeline.uptime[i], lastBucket+1)
+	for bucket := timeline.bucket(sim, start); start < end; bucket++ {
+		bucketEnd := min(end, time.Duration(bucket+1)*timeline.bucketWidth)
+		if bucket == lastBucket {
+			bucketEnd = end
+		}
+		timeline.uptime[i][bucket] += bucketEnd - start
+		start = bucketEnd
+	}
+}
+
+func (timeline *TimelineMetrics) sampleResources(sim *Simulation, unit *Unit) {
+	bucket := timeline.bucket(sim, sim.CurrentTime)
+	for i, resourceType := range timelineResourceTypes {
+		var value float64
+		switch resourceType {
+		case proto.ResourceType_ResourceTypeMana:
+			if !unit.HasManaBar() {
+				continue
+			}
+			value = unit.CurrentMana()
+		case proto.ResourceType_ResourceTypeEnergy:
+			if !unit.HasEnergyBar() {
+				continue
+			}
+			value = unit.CurrentEnergy()
+		case proto.ResourceType_ResourceTypeRage:
+			if !unit.HasRageBar() {
+				continue
+			}
+			value = unit.CurrentRage()
+		}
+		timeline.resources[i] = extendTimeline(timeline.resources[i], bucket+1)
+		timeline.resources[i][bucket] = value
+	}
+}
+
+// This should be called when a Sim iteration is complete.
+func (timeline *TimelineMetrics) doneIteration(sim *Simulation) {
+	n := timeline.numBuckets(sim)
+	timeline.iterations = extendTimeline(timeline.iterations, n)
+	timeline.seconds = extendTimeline(timeline.seconds, n)
+	timeline.damageSum = extendTimeline(timeline.damageSum, n)
+
+	for bucket := 0; bucket < n; bucket++ {
+		timeline.iterations[bucket]++
+		timeline.seconds[bucket] += min(timeline.bucketWidth, sim.Duration-time.Duration(bucket)*timeline.bucketWidth).Seconds()
+	}
+	for bucket, damage := range timeline.damage {
+		timeline.damageSum[bucket] += damage
+	}
+
+	for i, values := range timeline.resources {
+		if values == nil {
+			continue
+		}
+		timeline.resourceSums[i] = extendTimeline(timeline.resourceSums[i], n)
+		for bucket, value := range values[:min(n, len(values))] {
+			timeline.resourceSums[i][bucket] += value
+		}
+	}
+
+	for i, values := range timeline.uptime {
+		timeline.uptimeSums[i] = extendTimeline(timeline.uptimeSums[i], n)
+		for bucket, uptime := range values {
+			timeline.uptimeSums[i][bucket] += uptime.Seconds()
+		}
+	}
+}
+
+func (timeline *TimelineMetrics) ToProto() *proto.TimelineMetrics {
+	n := len(timeline.iterations)
+	timelineProto := &proto.TimelineMetrics{
+		BucketSeconds: timeline.bucketWidth.Seconds(),
+		Iterations:    timeline.iterations,
+		Seconds:       timeline.seconds,
+		Dps:           make([]float64, n),
+	}
+
+	for bucket, damage := range timeline.damageSum {
+		timelineProto.Dps[bucket] = damage / timeline.seconds[bucket]
+	}
+
+	for i, sums := range timeline.resourceSums {
+		if sums == nil {
+			continue
+		}
+		resource := &proto.ResourceTimeline{
+			Type:   timelineResourceTypes[i],
+			Values: make([]float64, n),
+		}
+		for bucket, sum := range sums {
+			resource.Values[bucket] = sum / float64(timeline.iterations[bucket])
+		}
+		timelineProto.Resources = append(timelineProto.Resources, resource)
+	}
+
+	for i, sums := range timeline.uptimeSums {
+		aura := &proto.AuraTimeline{
+			Id:     timeline.auraIDs[i].ToProto(),
+			Uptime: make([]float64, n),
+		}
+		hasUptime := false
+		for bucket, sum := range sums {
+			aura.Uptime[bucket] = sum / timeline.seconds[bucket]
+			hasUptime = hasUptime || sum > 0
+		}
+		// Only report the selected auras this unit actually had.
+		if hasUptime {
+			timelineProto.Auras = append(timelineProto.Auras, aura)
+		}
+	}
+
+	return timelineProto
+}
+
+// Creates the TimelineMetrics of every unit if requested, and schedules sampling
+// of their resources at the start of each bucket.
+func (sim *Simulation) initTimelineAction() {
+	if sim.Options.TimelineBucketSeconds <= 0 {
+		return
+	}
+
+	bucketWidth := DurationFromSeconds(sim.Options.TimelineBucketSeconds)
+	for _, unit := range sim.AllUnits {
+		if unit.Metrics.timeline == nil {
+			auraIDs := make([]ActionID, len(sim.Options.TimelineAuraIds))
+			for i, auraID := range sim.Options.TimelineAuraIds {
+				auraIDs[i] = ProtoToActionID(auraID)
+			}
+			unit.Metrics.timeline = newTimelineMetrics(bucketWidth, auraIDs)
+		}
+	}
+
+	pa := &PendingAction{
+		NextActionAt: 0,
+		Priority:     ActionPriorityPrePull + 1, // Sample before anything else happens at the same time.
+	}
+	pa.OnAction = func(sim *Simulation) {
+		for _, unit := range sim.AllUnits {
+			if unit.IsEnabled() {
+				unit.Metrics.timeline.sampleResources(sim, unit)
+			}
+		}
+
+		pa.NextActionAt = sim.CurrentTime + bucketWidth
+		sim.AddPendingAction(pa)
+	}
+	sim.AddPendingAction(pa)
+}
+
+// Merges add into base, weighting each bucket by how many iterations or seconds of
+// the fight it covered.
+func combineTimelineMetrics(base *proto.TimelineMetrics, add *proto.TimelineMetrics) {
+	n := max(len(base.Iterations), len(add.Iterations))
+	base.Iterations = extendTimeline(base.Iterations, n)
+	base.Seconds = extendTimeline(base.Seconds, n)
+	addIterations := extendTimeline(add.Iterations, n)
+	addSeconds := extendTimeline(add.Seconds, n)
+
+	// Values missing from add, e.g. for an aura which never came up, count as 0.
+	combine := func(baseValues []float64, addValues []float64, weight func(bucket int) (float64, float64)) []float64 {
+		baseValues = extendTimeline(baseValues, n)
+		addValues = extendTimeline(addValues, n)
+		for bucket := range baseValues {
+			if baseWeight, addWeight := weight(bucket); baseWeight+addWeight > 0 {
+				baseValues[bucket] = (baseValues[bucket]*baseWeight + addValues[bucket]*addWeight) / (baseWeight + addWeight)
+			}
+		}
+		return baseValues
+	}
+	bySeconds := func(bucket int) (float64, float64) {
+		return base.Seconds[bucket], addSeconds[bucket]
+	}
+	byIterations := func(bucket int) (float64, float64) {
+		return float64(base.Iterations[bucket]), float64(addIterations[bucket])
+	}
+
+	base.Dps = combine(base.Dps, add.Dps, bySeconds)
+
+	for _, addResource := range add.Resources {
+		if !slices.ContainsFunc(base.Resources, func(resource *proto.ResourceTimeline) bool { return resource.Type == addResource.Type }) {
+			base.Resources = append(base.Resources, &proto.ResourceTimeline{Type: addResource.Type})
+		}
+	}
+	for _, baseResource := range base.Resources {
+		var addValues []float64
+		for _, addResource := range add.Resources {
+			if addResource.Type == baseResource.Type {
+				addValues = addResource.Values
+			}
+		}
+		baseResource.Values = combine(baseResource.Values, addValues, byIterations)
+	}
+
+	for _, addAura := range add.Auras {
+		if !slices.ContainsFunc(base.Auras, func(aura *proto.AuraTimeline) bool { return googleProto.Equal(aura.Id, addAura.Id) }) {
+			base.Auras = append(base.Auras, &proto.AuraTimeline{Id: addAura.Id})
+		}
+	}
+	for _, baseAura := range base.Auras {
+		var addValues []float64
+		for _, addAura := range add.Auras {
+			if googleProto.Equal(addAura.Id, baseAura.Id) {
+				addValues = addAura.Uptime
+			}
+		}
+		baseAura.Uptime = combine(baseAura.Uptime, addValues, bySeconds)
+	}
+
+	for bucket := range base.Iterations {
+		base.Iterations[bucket] += addIterations[bucket]
+		base.Seconds[bucket] += addSeconds[bucket]
+	}
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"math"
+	"testing"
+
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+func timelineTestRequest() *proto.RaidSimRequest {
+	rsr := fakeSimRequest()
+	rsr.SimOptions.Iterations = 20
+	rsr.Encounter.Duration = 60
+	rsr.Encounter.DurationVariation = 20
+	rsr.Raid.Parties[0].Players[0].Rotation = fakeDotRotation()
+	rsr.SimOptions.TimelineBucketSeconds = 10
+	rsr.SimOptions.TimelineAuraIds = []*proto.ActionID{{RawId: &proto.ActionID_SpellId{SpellId: 42}}}
+	return rsr
+}
+
+func TestTimelineMetrics(t *testing.T) {
+	rsr := timelineTestRequest()
+	rsr.Encounter.DurationVariation = 0
+	result := RunRaidSim(rsr)
+	if result.Error != nil {
+		t.Fatalf("Sim failed: %s", result.Error.Message)
+	}
+
+	player := result.RaidMetrics.Parties[0].Players[0]
+	timeline := player.Timeline
+	if len(timeline.Iterations) != 6 || timeline.Iterations[5] != rsr.SimOptions.Iterations {
+		t.Fatalf("Expected 6 buckets of %d iterations, got %v", rsr.SimOptions.Iterations, timeline.Iterations)
+	}
+
+	totalDamage := 0.0
+	for bucket, dps := range timeline.Dps {
+		totalDamage += dps * timeline.Seconds[bucket]
+	}
+	if dps := totalDamage / (60 * float64(rsr.SimOptions.Iterations)); math.Abs(dps-player.Dps.Avg) > 1e-6 {
+		t.Errorf("Timeline adds up to %0.3f DPS, expected %0.3f", dps, player.Dps.Avg)
+	}
+
+	targetTimeline := result.EncounterMetrics.Targets[0].Timeline
+	if len(targetTimeline.Auras) != 1 {
+		t.Fatalf("Expected the dot uptime on the target, got %v", targetTimeline.Auras)
+	}
+	for bucket, uptime := range targetTimeline.Auras[0].Uptime {
+		if uptime <= 0 || uptime > 1 {
+			t.Errorf("Dot uptime in bucket %d is %0.3f", bucket, uptime)
+		}
+	}
+}
+
+func TestTimelineMetricsConcurrent(t *testing.T) {
+	rsr := timelineTestRequest()
+	rsr.SimOptions.IsTest = true // Splits into 3 sims.
+	expected := RunRaidSim(rsr).RaidMetrics.Parties[0].Players[0].Timeline
+	actual := RunRaidSimConcurrent(rsr).RaidMetrics.Parties[0].Players[0].Timeline
+
+	if len(actual.Iterations) != len(expected.Iterations) {
+		t.Fatalf("Expected %d buckets, got %d", len(expected.Iterations), len(actual.Iterations))
+	}
+	for bucket := range expected.Iterations {
+		if actual.Iterations[bucket] != expected.Iterations[bucket] || math.Abs(actual.Dps[bucket]-expected.Dps[bucket]) > 1e-6 {
+			t.Errorf("Bucket %d differs after combining: expected %d iterations at %0.3f DPS, got %d at %0.3f", bucket,
+				expected.Iterations[bucket], expected.Dps[bucket], actual.Iterations[bucket], actual.Dps[bucket])
+		}
+	}
+}
+
+func TestCombineTimelineMetrics(t *testing.T) {
+	auraID := &proto.ActionID{RawId: &proto.ActionID_SpellId{SpellId: 42}}
+	base := &proto.TimelineMetrics{BucketSeconds: 10}
+	combineTimelineMetrics(base, &proto.TimelineMetrics{
+		Iterations: []int32{3, 1},
+		Seconds:    []float64{30, 5},
+		Dps:        []float64{100, 200},
+		Resources:  []*proto.ResourceTimeline{{Type: proto.ResourceType_ResourceTypeMana, Values: []float64{1000, 400}}},
+		Auras:      []*proto.AuraTimeline{{Id: auraID, Uptime: []float64{0.5, 1}}},
+	})
+	combineTimelineMetrics(base, &proto.TimelineMetrics{
+		Iterations: []int32{1},
+		Seconds:    []float64{10},
+		Dps:        []float64{300},
+		Resources:  []*proto.ResourceTimeline{{Type: proto.ResourceType_ResourceTypeMana, Values: []float64{2000}}},
+	})
+
+	expect := func(name string, actual []float64, expected []float64) {
+		for bucket := range expected {
+			if math.Abs(actual[bucket]-expected[bucket]) > 1e-9 {
+				t.Errorf("Expected %s %v, got %v", name, expected, actual)
+				return
+			}
+		}
+	}
+	expect("dps", base.Dps, []float64{150, 200})
+	expect("seconds", base.Seconds, []float64{40, 5})
+	expect("mana", base.Resources[0].Values, []float64{1250, 400})
+	expect("uptime", base.Auras[0].Uptime, []float64{0.375, 1})
+}
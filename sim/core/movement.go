@@ -108,6 +108,7 @@ func (unit *Unit) initMovement() {
 			if unit.IsChanneling(sim) {
 				unit.ChanneledDot.Cancel(sim)
 			}
+			unit.InterruptHardcast(sim)
 			unit.AutoAttacks.CancelAutoSwing(sim)
 			unit.MovementHandler.Moving = true
 		},
@@ -141,27 +142,57 @@ func (unit *Unit) MoveTo(moveRange float64, sim *Simulation) {
 	}
 
 	moveDistance := moveRange - unit.DistanceFromTarget
-	moveTicks := math.Abs(moveDistance)
-	moveInterval := moveDistance / float64(moveTicks)
+	moveTicks := math.Ceil(math.Abs(moveDistance))
+	moveInterval := moveDistance / moveTicks
 
 	unit.MovementHandler.moveSpell.Cast(sim, unit.CurrentTarget)
 
 	sim.AddPendingAction(NewPeriodicAction(sim, PeriodicActionOptions{
-		Period:          time.Millisecond * time.Duration(1000/(unit.MovementHandler.MoveSpeed)),
+		Period:          unit.MovementHandler.yardDuration(),
 		NumTicks:        int(moveTicks),
 		TickImmediately: false,
 
 		OnAction: func(sim *Simulation) {
 			unit.DistanceFromTarget += moveInterval
-			unit.MovementHandler.moveAura.SetStacks(sim, int32(unit.DistanceFromTarget))
 
-			if unit.DistanceFromTarget == moveRange {
+			// Accumulated float steps may land just short of or past the range, so finish on reaching it.
+			if (moveInterval > 0 && unit.DistanceFromTarget >= moveRange) || (moveInterval < 0 && unit.DistanceFromTarget <= moveRange) {
+				unit.DistanceFromTarget = moveRange
+				unit.MovementHandler.moveAura.SetStacks(sim, int32(unit.DistanceFromTarget))
 				unit.MovementHandler.moveAura.Deactivate(sim)
+				return
 			}
+			unit.MovementHandler.moveAura.SetStacks(sim, int32(unit.DistanceFromTarget))
 		},
 	}))
 }
 
+// Moves the unit the given number of yards without changing its distance from its target,
+// e.g. to swap sides of the boss.
+func (unit *Unit) MoveDistance(yards float64, sim *Simulation) {
+	if yards <= 0 || unit.IsMoving() {
+		return
+	}
+
+	unit.MovementHandler.moveSpell.Cast(sim, unit.CurrentTarget)
+
+	StartDelayedAction(sim, DelayedActionOptions{
+		DoAt: sim.CurrentTime + unit.MovementHandler.MoveDuration(yards),
+		OnAction: func(sim *Simulation) {
+			unit.MovementHandler.moveAura.Deactivate(sim)
+		},
+	})
+}
+
+// Time it takes to move the given number of yards, at the current move speed.
+func (move *MovementHandler) MoveDuration(yards float64) time.Duration {
+	return time.Duration(math.Ceil(math.Abs(yards))) * move.yardDuration()
+}
+
+func (move *MovementHandler) yardDuration() time.Duration {
+	return time.Millisecond * time.Duration(1000/move.MoveSpeed)
+}
+
 // A move speed increase of 30% should be represented as 1.30 and a move speed slow of 70% should be respresented as 0.70
 func (unit *Unit) AddMoveSpeedModifier(actionId *ActionID, modifier float64) {
 	moveSpeedMod := MoveModifier{
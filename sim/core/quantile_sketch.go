@@ -0,0 +1,105 @@
+package core
+
+import (
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+// Relative accuracy of percentiles estimated by a quantileSketch.
+const quantileSketchAccuracy = 0.005
+
+var (
+	quantileSketchGamma    = (1 + quantileSketchAccuracy) / (1 - quantileSketchAccuracy)
+	quantileSketchLogGamma = math.Log(quantileSketchGamma)
+)
+
+// quantileSketch summarizes a distribution of values in logarithmically sized bins, so that each
+// percentile estimate is within quantileSketchAccuracy of the true value (relative). Unlike a
+// sample, two sketches can be merged exactly, e.g. across concurrent sims.
+type quantileSketch struct {
+	bins      map[int32]int64
+	zeroCount int64
+	count     int64
+}
+
+func newQuantileSketch() quantileSketch {
+	return quantileSketch{bins: make(map[int32]int64)}
+}
+
+func (sketch *quantileSketch) add(v float64) {
+	sketch.count++
+	if v <= 0 {
+		sketch.zeroCount++
+		return
+	}
+	sketch.bins[int32(math.Ceil(math.Log(v)/quantileSketchLogGamma))]++
+}
+
+func (sketch *quantileSketch) merge(other *proto.QuantileSketch) {
+	if other == nil {
+		return
+	}
+	for key, count := range other.Bins {
+		sketch.bins[key] += count
+		sketch.count += count
+	}
+	sketch.zeroCount += other.ZeroCount
+	sketch.count += other.ZeroCount
+}
+
+// Returns the estimated q-quantile, for 0 <= q <= 1.
+func (sketch *quantileSketch) quantile(q float64) float64 {
+	if sketch.count == 0 {
+		return 0
+	}
+
+	rank := int64(q * float64(sketch.count-1))
+	if rank < sketch.zeroCount {
+		return 0
+	}
+
+	keys := make([]int32, 0, len(sketch.bins))
+	for key := range sketch.bins {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	seen := sketch.zeroCount
+	for _, key := range keys {
+		seen += sketch.bins[key]
+		if seen > rank {
+			// Midpoint of the bin, in terms of relative error.
+			return 2 * math.Pow(quantileSketchGamma, float64(key)) / (quantileSketchGamma + 1)
+		}
+	}
+	return 2 * math.Pow(quantileSketchGamma, float64(keys[len(keys)-1])) / (quantileSketchGamma + 1)
+}
+
+func (sketch *quantileSketch) ToProto() *proto.QuantileSketch {
+	return &proto.QuantileSketch{
+		Bins:      maps.Clone(sketch.bins),
+		ZeroCount: sketch.zeroCount,
+	}
+}
+
+// Fills in the percentiles and the confidence interval of the mean from the sketch, avg, stdev and n of distMetrics.
+func setDistributionPercentiles(distMetrics *proto.DistributionMetrics, sketch *quantileSketch) {
+	clamp := func(v float64) float64 {
+		return max(distMetrics.Min, min(distMetrics.Max, v))
+	}
+	distMetrics.P5 = clamp(sketch.quantile(0.05))
+	distMetrics.P25 = clamp(sketch.quantile(0.25))
+	distMetrics.P50 = clamp(sketch.quantile(0.5))
+	distMetrics.P75 = clamp(sketch.quantile(0.75))
+	distMetrics.P95 = clamp(sketch.quantile(0.95))
+
+	halfWidth := 0.0
+	if n := distMetrics.AggregatorData.GetN(); n > 0 {
+		halfWidth = 1.96 * distMetrics.Stdev / math.Sqrt(float64(n))
+	}
+	distMetrics.AvgCi95Low = distMetrics.Avg - halfWidth
+	distMetrics.AvgCi95High = distMetrics.Avg + halfWidth
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+func TestQuantileSketchAccuracy(t *testing.T) {
+	r := NewSplitMix(1234)
+	values := make([]float64, 0, 10000)
+	sketch := newQuantileSketch()
+	for i := 0; i < cap(values); i++ {
+		v := 800 + 400*r.NextFloat64()
+		values = append(values, v)
+		sketch.add(v)
+	}
+	slices.Sort(values)
+
+	for _, q := range []float64{0.05, 0.25, 0.5, 0.75, 0.95} {
+		expected := values[int(q*float64(len(values)-1))]
+		if actual := sketch.quantile(q); math.Abs(actual-expected) > expected*quantileSketchAccuracy {
+			t.Errorf("quantile(%.2f) = %.2f, expected %.2f", q, actual, expected)
+		}
+	}
+}
+
+func TestQuantileSketchMerge(t *testing.T) {
+	r := NewSplitMix(5678)
+	whole := newQuantileSketch()
+	halves := []quantileSketch{newQuantileSketch(), newQuantileSketch()}
+	for i := 0; i < 1000; i++ {
+		v := 1000 * r.NextFloat64()
+		if i%7 == 0 {
+			v = 0
+		}
+		whole.add(v)
+		halves[i%2].add(v)
+	}
+
+	merged := newQuantileSketch()
+	for _, half := range halves {
+		merged.merge(half.ToProto())
+	}
+	// Results may be modified, e.g. by combining them, without changing the sketch.
+	for key := range merged.bins {
+		merged.ToProto().Bins[key] = 0
+	}
+
+	for _, q := range []float64{0, 0.05, 0.25, 0.5, 0.75, 0.95, 1} {
+		if merged.quantile(q) != whole.quantile(q) {
+			t.Errorf("merged quantile(%.2f) = %.2f, expected %.2f", q, merged.quantile(q), whole.quantile(q))
+		}
+	}
+}
+
+func TestQuantileSketchOnlyForCombining(t *testing.T) {
+	rsr := fakeSimRequest()
+	rsr.Raid.Parties[0].Players[0].Rotation = fakeDotRotation()
+	rsr.Encounter.Duration = 30
+	rsr.SimOptions.Iterations = 30
+	rsr.SimOptions.IsTest = true // Splits concurrent sims 3 ways.
+
+	for name, result := range map[string]*proto.RaidSimResult{
+		"single":     RunRaidSim(rsr),
+		"concurrent": RunRaidSimConcurrent(rsr),
+	} {
+		if result.Error != nil {
+			t.Fatalf("%s sim failed: %s", name, result.Error.Message)
+		}
+		dps := result.RaidMetrics.Dps
+		if dps.Sketch != nil {
+			t.Errorf("%s sim returned the quantile sketch with %d bins", name, len(dps.Sketch.Bins))
+		}
+		if dps.P50 <= 0 {
+			t.Errorf("%s sim returned no percentiles, p50 %0.1f", name, dps.P50)
+		}
+	}
+}
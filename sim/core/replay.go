@@ -0,0 +1,73 @@
+package core
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/wowsims/sod/sim/core/proto"
+	"github.com/wowsims/sod/sim/core/simsignals"
+	googleProto "google.golang.org/protobuf/proto"
+)
+
+// Relative difference up to which a replayed DPS still matches the recorded one.
+const replayDpsTolerance = 1e-9
+
+func replayIteration(request *proto.ReplayIterationRequest, signals simsignals.Signals) *proto.ReplayIterationResult {
+	if request.Request == nil {
+		return &proto.ReplayIterationResult{Error: &proto.ErrorOutcome{Message: "replay: missing raid sim request"}}
+	}
+	// A random seed of 0 means "seed from the clock", and is never recorded for an iteration.
+	if request.Seed == 0 {
+		return &proto.ReplayIterationResult{Error: &proto.ErrorOutcome{Message: "replay: seed must be non-zero"}}
+	}
+
+	raidIndex := int32(-1)
+	if ref := request.GetPlayer(); ref != nil {
+		if ref.Type != proto.UnitReference_Player {
+			return &proto.ReplayIterationResult{
+				Error: &proto.ErrorOutcome{
+					Message: fmt.Sprintf("replay: player reference has type %s, expected %s", ref.Type, proto.UnitReference_Player),
+				},
+			}
+		}
+		if pl := bulkSimPlayer(request.Request, ref.Index); pl == nil || pl.Name == "" {
+			return &proto.ReplayIterationResult{
+				Error: &proto.ErrorOutcome{
+					Message: fmt.Sprintf("replay: no player with raid index %d", ref.Index),
+				},
+			}
+		}
+		raidIndex = ref.Index
+	}
+
+	// Each iteration reseeds the sim (and all labeled rands) from its own seed, see reseedRands.
+	// Starting a fresh sim from that seed thus runs the exact same iteration as its first one.
+	rsr := googleProto.Clone(request.Request).(*proto.RaidSimRequest)
+	if rsr.SimOptions == nil {
+		rsr.SimOptions = &proto.SimOptions{}
+	}
+	rsr.SimOptions.Iterations = 1
+	rsr.SimOptions.RandomSeed = request.Seed
+	rsr.SimOptions.Debug = true
+	rsr.SimOptions.DebugFirstIteration = true
+
+	result := RunSim(rsr, nil, signals)
+	if result.Error != nil {
+		return &proto.ReplayIterationResult{Result: result, Error: result.Error}
+	}
+
+	dps := result.RaidMetrics.Dps.Avg
+	if raidIndex >= 0 {
+		dps = result.RaidMetrics.Parties[raidIndex/5].Players[raidIndex%5].Dps.Avg
+	}
+
+	return &proto.ReplayIterationResult{
+		Result:     result,
+		Dps:        dps,
+		DpsMatches: request.ExpectedDps != 0 && replayDpsMatches(dps, request.ExpectedDps),
+	}
+}
+
+func replayDpsMatches(dps float64, expected float64) bool {
+	return math.Abs(dps-expected) <= replayDpsTolerance*math.Max(1, math.Abs(expected))
+}
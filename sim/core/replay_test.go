@@ -0,0 +1,107 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wowsims/sod/sim/core/proto"
+)
+
+func replayTestRequest(useLabeledRands bool) *proto.RaidSimRequest {
+	fakeDot := &proto.ActionID{RawId: &proto.ActionID_SpellId{SpellId: 42}}
+	return &proto.RaidSimRequest{
+		SimOptions: &proto.SimOptions{
+			Iterations:      20,
+			RandomSeed:      100,
+			UseLabeledRands: useLabeledRands,
+		},
+		Raid: &proto.Raid{
+			Parties: []*proto.Party{
+				{
+					Players: []*proto.Player{
+						{
+							Name:      "Caster",
+							Class:     proto.Class_ClassShaman,
+							Consumes:  &proto.Consumes{},
+							Buffs:     &proto.IndividualBuffs{},
+							Spec:      &proto.Player_ElementalShaman{},
+							Equipment: &proto.EquipmentSpec{},
+							Rotation: &proto.APLRotation{
+								Type: proto.APLRotation_TypeAPL,
+								PriorityList: []*proto.APLListItem{
+									{Action: &proto.APLAction{
+										Condition: &proto.APLValue{Value: &proto.APLValue_Not{Not: &proto.APLValueNot{
+											Val: &proto.APLValue{Value: &proto.APLValue_DotIsActive{DotIsActive: &proto.APLValueDotIsActive{SpellId: fakeDot}}},
+										}}},
+										Action: &proto.APLAction_CastSpell{CastSpell: &proto.APLActionCastSpell{SpellId: fakeDot}},
+									}},
+								},
+							},
+						},
+					},
+					Buffs: &proto.PartyBuffs{},
+				},
+			},
+		},
+		Encounter: &proto.Encounter{
+			Targets: []*proto.Target{
+				{Name: "target", Level: 63, MobType: proto.MobType_MobTypeDemon},
+			},
+			Duration:          60,
+			DurationVariation: 20,
+		},
+	}
+}
+
+func TestReplayIteration(t *testing.T) {
+	for _, useLabeledRands := range []bool{false, true} {
+		rsr := replayTestRequest(useLabeledRands)
+		result := RunRaidSim(rsr)
+		if result.Error != nil {
+			t.Fatalf("Sim failed: %s", result.Error.Message)
+		}
+
+		playerDps := result.RaidMetrics.Parties[0].Players[0].Dps
+		if playerDps.Min == playerDps.Max {
+			t.Fatalf("Expected iterations to differ, all had %0.3f DPS", playerDps.Min)
+		}
+
+		for _, iteration := range []struct {
+			seed int64
+			dps  float64
+		}{
+			{playerDps.MinSeed, playerDps.Min},
+			{playerDps.MaxSeed, playerDps.Max},
+		} {
+			replay := ReplayIteration(&proto.ReplayIterationRequest{
+				Request:     rsr,
+				Seed:        iteration.seed,
+				ExpectedDps: iteration.dps,
+				Player:      &proto.UnitReference{Type: proto.UnitReference_Player, Index: 0},
+			})
+			if replay.Error != nil {
+				t.Fatalf("Replay failed: %s", replay.Error.Message)
+			}
+			if !replay.DpsMatches {
+				t.Errorf("Replay of seed %d (labeled rands: %t) had %0.3f DPS, expected %0.3f", iteration.seed, useLabeledRands, replay.Dps, iteration.dps)
+			}
+			if !strings.Contains(replay.Result.Logs, "Casting") {
+				t.Errorf("Replay of seed %d is missing its logs", iteration.seed)
+			}
+		}
+	}
+}
+
+func TestReplayIterationErrors(t *testing.T) {
+	rsr := replayTestRequest(false)
+	requests := []*proto.ReplayIterationRequest{
+		{Request: rsr},
+		{Request: rsr, Seed: 1, Player: &proto.UnitReference{Type: proto.UnitReference_Player, Index: 3}},
+		{Request: rsr, Seed: 1, Player: &proto.UnitReference{Type: proto.UnitReference_Target, Index: 0}},
+	}
+	for _, request := range requests {
+		if replay := ReplayIteration(request); replay.Error == nil {
+			t.Errorf("Expected an error replaying %v", request)
+		}
+	}
+}
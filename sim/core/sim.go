@@ -9,7 +9,6 @@ import (
 	"runtime/debug"
 	"slices"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/wowsims/sod/sim/core/proto"
@@ -41,7 +40,8 @@ type Simulation struct {
 	ProgressReport func(*proto.ProgressMetrics)
 	Signals        simsignals.Signals
 
-	Log func(string, ...interface{})
+	Log       func(string, ...interface{})
+	combatLog []*proto.CombatLogEvent
 
 	executePhase int32 // 20, 25, or 35 for the respective execute range, 100 otherwise
 
@@ -53,6 +53,10 @@ type Simulation struct {
 	endOfCombatDuration time.Duration
 	endOfCombatDamage   float64
 
+	currentPhase      *EncounterPhase
+	nextPhaseDuration time.Duration
+	nextPhaseDamage   float64
+
 	minTrackerTime time.Duration
 	trackers       []*auraTracker
 
@@ -284,19 +288,13 @@ func (sim *Simulation) Reseed(seed int64) {
 func (sim *Simulation) run() *proto.RaidSimResult {
 	t0 := time.Now()
 
-	logsBuffer := &strings.Builder{}
+	sim.combatLog = nil
 	if sim.Options.Debug || sim.Options.DebugFirstIteration {
 		sim.Log = func(message string, vals ...interface{}) {
-			logsBuffer.WriteString(fmt.Sprintf("[%0.2f] "+message+"\n", append([]interface{}{sim.CurrentTime.Seconds()}, vals...)...))
+			sim.LogEvent(nil, &proto.CombatLogEvent{Event: &proto.CombatLogEvent_Message{Message: fmt.Sprintf(message, vals...)}})
 		}
 	}
 
-	// Uncomment this to print logs directly to console.
-	// sim.Options.Debug = true
-	// sim.Log = func(message string, vals ...interface{}) {
-	// 	fmt.Printf(fmt.Sprintf("[%0.1f] "+message+"\n", append([]interface{}{sim.CurrentTime.Seconds()}, vals...)...))
-	// }
-
 	sim.runOnce()
 	firstIterationDuration := sim.Duration
 	if sim.Encounter.EndFightAtHealth != 0 {
@@ -344,11 +342,15 @@ func (sim *Simulation) run() *proto.RaidSimResult {
 		RaidMetrics:      sim.Raid.GetMetrics(),
 		EncounterMetrics: sim.Encounter.GetMetricsProto(),
 
-		Logs:                   logsBuffer.String(),
 		FirstIterationDuration: firstIterationDuration.Seconds(),
 		AvgIterationDuration:   totalDuration.Seconds() / float64(sim.Options.Iterations),
 		IterationsDone:         sim.Options.Iterations,
 	}
+	if sim.Options.StructuredCombatLog {
+		result.CombatLog = sim.combatLog
+	} else {
+		result.Logs = FormatCombatLog(sim.combatLog)
+	}
 
 	// Final progress report
 	if sim.ProgressReport != nil {
@@ -408,6 +410,7 @@ func (sim *Simulation) reset() {
 	}
 
 	sim.CurrentTime = 0
+	sim.resetEncounterPhases()
 
 	sim.trackers = sim.trackers[:0]
 	sim.minTrackerTime = NeverExpires
@@ -419,8 +422,11 @@ func (sim *Simulation) reset() {
 	sim.minTaskTime = NeverExpires
 
 	sim.Environment.reset(sim)
+	sim.scheduleEncounterMovements()
 
 	sim.initManaTickAction()
+	sim.initTimelineAction()
+	sim.initAPLMetrics()
 }
 
 func (sim *Simulation) PrePull() {
@@ -557,6 +563,10 @@ func (sim *Simulation) advance(nextTime time.Duration) {
 		}
 	}
 
+	if sim.CurrentTime >= sim.nextPhaseDuration || sim.Encounter.DamageTaken >= sim.nextPhaseDamage {
+		sim.startEncounterPhases()
+	}
+
 	if sim.CurrentTime >= sim.minTrackerTime {
 		sim.minTrackerTime = NeverExpires
 		for _, t := range sim.trackers {
@@ -34,6 +34,8 @@ func SplitSimRequestForConcurrency(request *proto.RaidSimRequest, splitCount int
 
 	split[0] = googleProto.Clone(request).(*proto.RaidSimRequest)
 	split[0].SimOptions.Iterations = iterPerSplit + request.SimOptions.Iterations%splitCount
+	// Percentiles of the combined results are estimated from the merged sketches.
+	split[0].SimOptions.SaveQuantileSketch = splitCount > 1
 
 	// Sims increment their seed each iteration. Offset starting seed of each split to emulate that.
 	nextStartSeed := split[0].SimOptions.RandomSeed + int64(split[0].SimOptions.Iterations)
@@ -42,6 +44,7 @@ func SplitSimRequestForConcurrency(request *proto.RaidSimRequest, splitCount int
 		split[i] = googleProto.Clone(request).(*proto.RaidSimRequest)
 		split[i].SimOptions.Iterations = iterPerSplit
 		split[i].SimOptions.DebugFirstIteration = false // No logs
+		split[i].SimOptions.SaveQuantileSketch = true
 		split[i].SimOptions.RandomSeed = nextStartSeed
 		nextStartSeed += int64(split[i].SimOptions.Iterations)
 	}
@@ -63,6 +66,7 @@ func (rsrc *raidSimResultCombiner) newDistMetrics() *proto.DistributionMetrics {
 		Hist:           make(map[int32]int32),
 		AllValues:      make([]float64, 0),
 		AggregatorData: &proto.AggregatorData{},
+		Sketch:         &proto.QuantileSketch{Bins: make(map[int32]int64)},
 	}
 }
 
@@ -132,10 +136,24 @@ func (rsrc *raidSimResultCombiner) combineDistMetrics(base *proto.DistributionMe
 
 	base.AllValues = append(base.AllValues, add.AllValues...)
 
+	if add.Sketch != nil {
+		for key, count := range add.Sketch.Bins {
+			base.Sketch.Bins[key] += count
+		}
+		base.Sketch.ZeroCount += add.Sketch.ZeroCount
+	}
+
 	base.AggregatorData.N += add.AggregatorData.N
 	base.AggregatorData.SumSq += add.AggregatorData.SumSq
 	if isLast {
 		base.Stdev = math.Sqrt(base.AggregatorData.SumSq/float64(base.AggregatorData.N) - base.Avg*base.Avg)
+
+		sketch := newQuantileSketch()
+		sketch.merge(base.Sketch)
+		setDistributionPercentiles(base, &sketch)
+
+		// The sketch is only needed for combining.
+		base.Sketch = nil
 	}
 }
 
@@ -256,6 +274,9 @@ func (rsrc *raidSimResultCombiner) combineUnitMetrics(base *proto.UnitMetrics, a
 
 	base.SecondsOomAvg += add.SecondsOomAvg * weight
 	base.ChanceOfDeath += add.ChanceOfDeath * weight
+	base.SecondsPresentAvg += add.SecondsPresentAvg * weight
+	base.AggroGainedAvg += add.AggroGainedAvg * weight
+	base.SecondsWithAggroAvg += add.SecondsWithAggroAvg * weight
 
 	for _, addAction := range add.Actions {
 		rsrc.addActionMetrics(base, addAction)
@@ -272,6 +293,20 @@ func (rsrc *raidSimResultCombiner) combineUnitMetrics(base *proto.UnitMetrics, a
 	for i, addPet := range add.Pets {
 		rsrc.combineUnitMetrics(base.Pets[i], addPet, isLast, weight)
 	}
+
+	if add.Timeline != nil {
+		if base.Timeline == nil {
+			base.Timeline = &proto.TimelineMetrics{BucketSeconds: add.Timeline.BucketSeconds}
+		}
+		combineTimelineMetrics(base.Timeline, add.Timeline)
+	}
+
+	if add.AplMetrics != nil {
+		if base.AplMetrics == nil {
+			base.AplMetrics = &proto.APLMetrics{}
+		}
+		combineAPLMetrics(base.AplMetrics, add.AplMetrics, weight)
+	}
 }
 
 func (rsrc *raidSimResultCombiner) AddResult(result *proto.RaidSimResult, isLast bool, weight float64) {
@@ -295,7 +330,12 @@ func (rsrc *raidSimResultCombiner) AddResult(result *proto.RaidSimResult, isLast
 	rsrc.Combined.IterationsDone += result.IterationsDone
 
 	if rsrc.Debug {
-		rsrc.Combined.Logs += "-SIMSTART-\n" + result.Logs
+		// Results only have one of the logs, depending on SimOptions.StructuredCombatLog.
+		if len(result.CombatLog) > 0 {
+			rsrc.Combined.CombatLog = append(rsrc.Combined.CombatLog, result.CombatLog...)
+		} else {
+			rsrc.Combined.Logs += "-SIMSTART-\n" + result.Logs
+		}
 	}
 }
 
@@ -314,6 +354,7 @@ func (rsrc *raidSimResultCombiner) SetBaseResult(baseRsr *proto.RaidSimResult) {
 
 	if !rsrc.Debug {
 		newRsr.Logs = baseRsr.Logs
+		newRsr.CombatLog = baseRsr.CombatLog
 	}
 
 	for i, party := range baseRsr.RaidMetrics.Parties {
@@ -21,6 +21,11 @@ func (s *triggerSignal) IsTriggered() bool {
 	return false
 }
 
+// Done returns a channel that is closed once the signal has been triggered.
+func (s *triggerSignal) Done() <-chan struct{} {
+	return s.channel
+}
+
 type Signals struct {
 	Abort triggerSignal
 }
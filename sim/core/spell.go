@@ -571,6 +571,11 @@ func (spell *Spell) CanCast(sim *Simulation, target *Unit) bool {
 		return false
 	}
 
+	// Adds can only be targeted while they are spawned.
+	if target != nil && target.Type == EnemyUnit && !target.IsEnabled() {
+		return false
+	}
+
 	if spell.ExtraCastCondition != nil && !spell.ExtraCastCondition(sim, target) {
 		//if sim.Log != nil {
 		//	sim.Log("Cant cast because of extra condition")
@@ -407,11 +407,20 @@ func (spell *Spell) CalcAndDealOutcome(sim *Simulation, target *Unit, outcomeApp
 
 // Applies the fully computed spell result to the sim.
 func (spell *Spell) dealDamageInternal(sim *Simulation, isPeriodic bool, result *SpellResult) {
+	// Targets which haven't spawned yet or have despawned can't be hit, e.g. by AOE spells.
+	if result.Target.Type == EnemyUnit && !result.Target.IsEnabled() {
+		spell.DisposeResult(result)
+		return
+	}
+
 	isPeriodic = isPeriodic || spell.Flags.Matches(SpellFlagTreatAsPeriodic)
 	isPartialResist := result.DidResist()
 
 	if sim.CurrentTime >= 0 {
 		spell.SpellMetrics[result.Target.UnitIndex].TotalDamage += result.Damage
+		if timeline := spell.Unit.Metrics.timeline; timeline != nil && spell.Unit.IsOpponent(result.Target) {
+			timeline.addDamage(sim, result.Damage)
+		}
 		if isPartialResist {
 			spell.SpellMetrics[result.Target.UnitIndex].TotalResistedDamage += result.Damage
 		}
@@ -443,6 +452,9 @@ func (spell *Spell) dealDamageInternal(sim *Simulation, isPeriodic bool, result
 			spell.SpellMetrics[result.Target.UnitIndex].TotalCrushDamage += result.Damage
 		}
 		spell.SpellMetrics[result.Target.UnitIndex].TotalThreat += result.Threat
+		if result.Target.Type == EnemyUnit {
+			sim.Encounter.Targets[result.Target.Index].AddThreat(sim, spell.Unit, result.Threat)
+		}
 	}
 
 	// Mark total damage done in raid so far for health based fights.
@@ -452,11 +464,7 @@ func (spell *Spell) dealDamageInternal(sim *Simulation, isPeriodic bool, result
 	}
 
 	if sim.Log != nil && !spell.Flags.Matches(SpellFlagNoLogs) {
-		if isPeriodic {
-			spell.Unit.Log(sim, "%s %s tick %s (SpellSchool: %d). (Threat: %0.3f)", result.Target.LogLabel(), spell.ActionID, result.DamageString(), spell.SpellSchool, result.Threat)
-		} else {
-			spell.Unit.Log(sim, "%s %s %s (SpellSchool: %d). (Threat: %0.3f)", result.Target.LogLabel(), spell.ActionID, result.DamageString(), spell.SpellSchool, result.Threat)
-		}
+		spell.logSpellResult(sim, result, isPeriodic, false)
 	}
 
 	if !spell.Flags.Matches(SpellFlagNoOnDamageDealt) {
@@ -556,16 +564,16 @@ func (spell *Spell) dealHealingInternal(sim *Simulation, isPeriodic bool, result
 	}
 	spell.SpellMetrics[result.Target.UnitIndex].TotalHealing += result.Damage
 	spell.SpellMetrics[result.Target.UnitIndex].TotalThreat += result.Threat
+	if result.Threat != 0 && len(sim.Encounter.ActiveTargetUnits) > 0 {
+		// Healing threat is split between all enemies.
+		sim.Encounter.addThreatToAll(sim, spell.Unit, result.Threat/float64(len(sim.Encounter.ActiveTargetUnits)))
+	}
 	if result.Target.HasHealthBar() {
 		result.Target.GainHealth(sim, result.Damage, spell.HealthMetrics(result.Target))
 	}
 
 	if sim.Log != nil {
-		if isPeriodic {
-			spell.Unit.Log(sim, "%s %s tick %s. (Threat: %0.3f)", result.Target.LogLabel(), spell.ActionID, result.HealingString(), result.Threat)
-		} else {
-			spell.Unit.Log(sim, "%s %s %s. (Threat: %0.3f)", result.Target.LogLabel(), spell.ActionID, result.HealingString(), result.Threat)
-		}
+		spell.logSpellResult(sim, result, isPeriodic, true)
 	}
 
 	if isPeriodic {
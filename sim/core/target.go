@@ -1,6 +1,7 @@
 package core
 
 import (
+	"slices"
 	"strconv"
 	"time"
 
@@ -14,6 +15,9 @@ type Encounter struct {
 	Targets           []*Target
 	TargetUnits       []*Unit
 
+	// Targets which are currently spawned, in the same order as TargetUnits.
+	ActiveTargetUnits []*Unit
+
 	ExecuteProportion_20 float64
 	ExecuteProportion_25 float64
 	ExecuteProportion_35 float64
@@ -25,6 +29,9 @@ type Encounter struct {
 	// In health fight: set to true until we get something to base on
 	DurationIsEstimate bool
 
+	Phases    []*EncounterPhase
+	Movements []*EncounterMovement
+
 	// Value to multiply by, for damage spells which are subject to the aoe cap.
 	aoeCapMultiplier float64
 }
@@ -56,6 +63,13 @@ func NewEncounter(options *proto.Encounter) Encounter {
 		encounter.Targets = append(encounter.Targets, target)
 		encounter.TargetUnits = append(encounter.TargetUnits, &target.Unit)
 	}
+	for _, phaseOptions := range options.Phases {
+		encounter.AddPhase(newEncounterPhase(phaseOptions))
+	}
+	for _, movementOptions := range options.Movements {
+		encounter.AddMovement(newEncounterMovement(movementOptions))
+	}
+
 	if len(encounter.Targets) == 0 {
 		// Add a dummy target. The only case where targets aren't specified is when
 		// computing character stats, and targets won't matter there.
@@ -64,6 +78,8 @@ func NewEncounter(options *proto.Encounter) Encounter {
 		encounter.TargetUnits = append(encounter.TargetUnits, &target.Unit)
 	}
 
+	encounter.ActiveTargetUnits = slices.Clone(encounter.TargetUnits)
+
 	if encounter.EndFightAtHealth > 0 {
 		// Until we pre-sim set duration to 10m
 		encounter.Duration = time.Minute * 10
@@ -79,7 +95,7 @@ func (encounter *Encounter) AOECapMultiplier() float64 {
 	return encounter.aoeCapMultiplier
 }
 func (encounter *Encounter) updateAOECapMultiplier() {
-	encounter.aoeCapMultiplier = min(10/float64(len(encounter.Targets)), 1)
+	encounter.aoeCapMultiplier = min(10/float64(len(encounter.ActiveTargetUnits)), 1)
 }
 
 func (encounter *Encounter) doneIteration(sim *Simulation) {
@@ -108,6 +124,23 @@ type Target struct {
 	Unit
 
 	AI TargetAI
+
+	// Spawn schedule, for adds which are only present for part of the fight.
+	SpawnTime       time.Duration
+	DespawnAfter    time.Duration
+	DiesAtHealth    bool
+	RespawnInterval time.Duration
+
+	spawnedAt     time.Duration
+	damageTaken   float64
+	despawnAction *PendingAction
+
+	// Whether the target attacks whoever holds aggro instead of only its tank.
+	SwitchTargetsOnThreat bool
+
+	tank       *Unit
+	threat     []float64 // indexed by UnitIndex
+	aggroSince time.Duration
 }
 
 func NewTarget(options *proto.Target, targetIndex int32) *Target {
@@ -148,9 +181,17 @@ func NewTarget(options *proto.Target, targetIndex int32) *Target {
 	target.PseudoStats.InFrontOfTarget = true
 	target.PseudoStats.DamageSpread = options.DamageSpread
 
+	target.SpawnTime = DurationFromSeconds(max(options.SpawnTime, 0))
+	target.DespawnAfter = DurationFromSeconds(max(options.DespawnAfter, 0))
+	target.DiesAtHealth = options.DiesAtHealth
+	target.RespawnInterval = DurationFromSeconds(max(options.RespawnInterval, 0))
+	target.SwitchTargetsOnThreat = options.SwitchTargetsOnThreat
+
 	preset := GetPresetTargetWithID(options.Id)
 	if preset != nil && preset.AI != nil {
 		target.AI = preset.AI()
+	} else if options.BossScript != nil {
+		target.AI = NewBossScriptAI(options.BossScript)()
 	}
 
 	return target
@@ -162,6 +203,8 @@ func (target *Target) Reset(sim *Simulation) {
 	if target.AI != nil {
 		target.AI.Reset(sim)
 	}
+	target.resetThreat(sim)
+	target.resetSpawns(sim)
 }
 
 func (target *Target) NextTarget() *Target {
@@ -17,7 +17,8 @@ func (target *Target) initialize(config *proto.Target) {
 		return
 	}
 
-	if target.CurrentTarget != nil {
+	// Targets without a tank which switch on threat only start attacking once someone pulls aggro.
+	if target.CurrentTarget != nil || target.SwitchTargetsOnThreat {
 		if config.SwingSpeed > 0 {
 			aaOptions := AutoAttackOptions{
 				MainHand: Weapon{
@@ -47,6 +48,9 @@ func (target *Target) initialize(config *proto.Target) {
 			},
 		}
 	}
+
+	target.registerSpawnSchedule()
+	target.registerThreatTable()
 }
 
 // Empty Agent interface functions.
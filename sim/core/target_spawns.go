@@ -0,0 +1,166 @@
+package core
+
+import (
+	"slices"
+
+	"github.com/wowsims/sod/sim/core/stats"
+)
+
+// Whether this target is only present for part of the fight.
+func (target *Target) HasSpawnSchedule() bool {
+	return target.SpawnTime > 0 || target.DespawnAfter > 0 || target.DiesAtHealth || target.RespawnInterval > 0
+}
+
+func (target *Target) registerSpawnSchedule() {
+	if !target.DiesAtHealth {
+		return
+	}
+
+	onDamageTaken := func(aura *Aura, sim *Simulation, spell *Spell, result *SpellResult) {
+		if !target.IsEnabled() || result.Damage <= 0 {
+			return
+		}
+
+		maxHealth := target.GetStat(stats.Health)
+		if target.damageTaken < maxHealth && target.damageTaken+result.Damage >= maxHealth {
+			// Despawning expires auras, so don't do it while they are still processing this hit.
+			StartDelayedAction(sim, DelayedActionOptions{
+				DoAt:     sim.CurrentTime,
+				Priority: ActionPriorityDOT,
+				OnAction: target.Despawn,
+			})
+		}
+		target.damageTaken += result.Damage
+	}
+
+	target.RegisterAura(Aura{
+		Label:    "Add Health",
+		Duration: NeverExpires,
+		OnReset: func(aura *Aura, sim *Simulation) {
+			aura.Activate(sim)
+		},
+		OnSpellHitTaken:       onDamageTaken,
+		OnPeriodicDamageTaken: onDamageTaken,
+	})
+}
+
+func (target *Target) resetSpawns(sim *Simulation) {
+	target.spawnedAt = 0
+	target.damageTaken = 0
+	target.despawnAction = nil
+
+	if !target.HasSpawnSchedule() {
+		return
+	}
+
+	if target.SpawnTime > 0 {
+		target.enabled = false
+		if target.gcdAction != nil {
+			target.CancelGCDTimer(sim)
+		}
+	} else {
+		target.scheduleDespawn(sim)
+	}
+
+	if target.SpawnTime > 0 || target.RespawnInterval > 0 {
+		pa := &PendingAction{
+			NextActionAt: target.SpawnTime,
+			Priority:     ActionPriorityDOT,
+		}
+		if target.SpawnTime == 0 {
+			pa.NextActionAt = target.RespawnInterval
+		}
+		pa.OnAction = func(sim *Simulation) {
+			target.Spawn(sim)
+			if target.RespawnInterval > 0 {
+				pa.NextActionAt = sim.CurrentTime + target.RespawnInterval
+				sim.AddPendingAction(pa)
+			}
+		}
+		sim.AddPendingAction(pa)
+	}
+}
+
+func (target *Target) scheduleDespawn(sim *Simulation) {
+	if target.DespawnAfter == 0 {
+		return
+	}
+	target.despawnAction = StartDelayedAction(sim, DelayedActionOptions{
+		DoAt:     sim.CurrentTime + target.DespawnAfter,
+		Priority: ActionPriorityDOT,
+		OnAction: target.Despawn,
+	})
+}
+
+// Spawns the target, if it isn't already. Can be used by encounter AIs to bring in adds.
+func (target *Target) Spawn(sim *Simulation) {
+	if target.enabled {
+		return
+	}
+
+	target.enabled = true
+	target.spawnedAt = sim.CurrentTime
+	target.damageTaken = 0
+	target.resetThreat(sim)
+	sim.Encounter.updateActiveTargets()
+
+	target.AutoAttacks.EnableAutoSwing(sim)
+	if target.gcdAction != nil {
+		target.SetGCDTimer(sim, sim.CurrentTime)
+	}
+	target.scheduleDespawn(sim)
+
+	if sim.Log != nil {
+		target.Log(sim, "Spawned")
+	}
+}
+
+// Despawns the target, if it is spawned. Its temporary auras, e.g. DoTs and debuffs, are removed.
+func (target *Target) Despawn(sim *Simulation) {
+	if !target.enabled {
+		return
+	}
+
+	target.Metrics.PresentTime += sim.CurrentTime - target.spawnedAt
+	target.endAggro(sim)
+	target.enabled = false
+	sim.Encounter.updateActiveTargets()
+
+	target.AutoAttacks.CancelAutoSwing(sim)
+	if target.gcdAction != nil {
+		target.CancelGCDTimer(sim)
+	}
+	if target.despawnAction != nil {
+		target.despawnAction.Cancel(sim)
+		target.despawnAction = nil
+	}
+
+	// Permanent auras, e.g. raid debuffs, are kept for the next spawn.
+	for _, aura := range slices.Clone(target.activeAuras) {
+		if aura.Duration != NeverExpires {
+			aura.Deactivate(sim)
+		}
+	}
+
+	if sim.Log != nil {
+		target.Log(sim, "Despawned")
+	}
+}
+
+func (target *Target) doneIteration(sim *Simulation) {
+	if target.enabled {
+		target.Metrics.PresentTime += sim.CurrentTime - max(target.spawnedAt, 0)
+	}
+	target.endAggro(sim)
+	target.Unit.doneIteration(sim)
+}
+
+func (encounter *Encounter) updateActiveTargets() {
+	encounter.ActiveTargetUnits = encounter.ActiveTargetUnits[:0]
+	for _, unit := range encounter.TargetUnits {
+		if unit.IsEnabled() {
+			encounter.ActiveTargetUnits = append(encounter.ActiveTargetUnits, unit)
+		}
+	}
+	encounter.updateAOECapMultiplier()
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/wowsims/sod/sim/core/proto"
+	"github.com/wowsims/sod/sim/core/simsignals"
+	"github.com/wowsims/sod/sim/core/stats"
+)
+
+func spawnsTestRequest(add *proto.Target) *proto.RaidSimRequest {
+	rsr := fakeSimRequest()
+	rsr.SimOptions.Iterations = 20
+	rsr.Encounter.Duration = 60
+	rsr.Encounter.Targets = append(rsr.Encounter.Targets, add)
+	rsr.Raid.Parties[0].Players[0].Rotation = &proto.APLRotation{
+		Type: proto.APLRotation_TypeAPL,
+		PriorityList: []*proto.APLListItem{
+			{Action: &proto.APLAction{Action: &proto.APLAction_Multidot{Multidot: &proto.APLActionMultidot{
+				SpellId: &proto.ActionID{RawId: &proto.ActionID_SpellId{SpellId: 42}},
+				MaxDots: 2,
+			}}}},
+		},
+	}
+	return rsr
+}
+
+func TestTargetSpawns(t *testing.T) {
+	rsr := spawnsTestRequest(&proto.Target{
+		Name:            "add",
+		Level:           60,
+		SpawnTime:       20,
+		DespawnAfter:    15,
+		RespawnInterval: 30,
+	})
+	sim := NewSim(rsr, simsignals.CreateSignals())
+
+	// Present from 20s to 35s, and again from 50s until the end.
+	numTargets := &APLValueNumberTargets{}
+	startFakeSimUntil(sim, time.Second*25)
+	if actual := numTargets.GetInt(sim); actual != 2 {
+		t.Errorf("Expected 2 targets at %s, got %d", sim.CurrentTime, actual)
+	}
+	runFakeSimUntil(sim, time.Second*40)
+	if actual := numTargets.GetInt(sim); actual != 1 {
+		t.Errorf("Expected 1 target at %s, got %d", sim.CurrentTime, actual)
+	}
+
+	result := RunRaidSim(rsr)
+	if result.Error != nil {
+		t.Fatalf("Sim failed: %s", result.Error.Message)
+	}
+
+	for i, expected := range []float64{60, 25} {
+		if actual := result.EncounterMetrics.Targets[i].SecondsPresentAvg; math.Abs(actual-expected) > 1e-6 {
+			t.Errorf("Expected target %d to be present for %0.1fs, got %0.3fs", i, expected, actual)
+		}
+	}
+
+	for _, aura := range result.EncounterMetrics.Targets[1].Auras {
+		if aura.Id.GetSpellId() == 42 && (aura.UptimeSecondsAvg <= 0 || aura.UptimeSecondsAvg > 25) {
+			t.Errorf("Expected the dot to be up on the add for at most 25s, got %0.3fs", aura.UptimeSecondsAvg)
+		}
+	}
+}
+
+func TestTargetDiesAtHealth(t *testing.T) {
+	addStats := stats.Stats{}
+	addStats[stats.Health] = 1
+	rsr := spawnsTestRequest(&proto.Target{
+		Name:         "add",
+		Level:        60,
+		Stats:        addStats[:],
+		DiesAtHealth: true,
+	})
+
+	result := RunRaidSim(rsr)
+	if result.Error != nil {
+		t.Fatalf("Sim failed: %s", result.Error.Message)
+	}
+
+	// The add dies to the first dot tick, unless it is resisted.
+	if present := result.EncounterMetrics.Targets[1].SecondsPresentAvg; present <= 0 || present >= 10 {
+		t.Errorf("Expected the add to die within a few seconds, was present for %0.3fs", present)
+	}
+	if present := result.EncounterMetrics.Targets[0].SecondsPresentAvg; present != 60 {
+		t.Errorf("Expected the boss to be present for the whole fight, got %0.3fs", present)
+	}
+}
@@ -0,0 +1,120 @@
+package core
+
+// Ratios of the aggro holder's threat at which players pull aggro.
+const (
+	MeleeAggroThreshold  = 1.1
+	RangedAggroThreshold = 1.3
+)
+
+func (target *Target) registerThreatTable() {
+	if !target.SwitchTargetsOnThreat {
+		return
+	}
+	target.tank = target.CurrentTarget
+	target.threat = make([]float64, len(target.Env.AllUnits))
+}
+
+func (target *Target) resetThreat(sim *Simulation) {
+	if !target.SwitchTargetsOnThreat {
+		return
+	}
+	clear(target.threat)
+	target.CurrentTarget = target.tank
+	target.aggroSince = sim.CurrentTime
+}
+
+// Returns the threat of a unit on this target, or 0 if the target doesn't switch targets on threat.
+func (target *Target) ThreatOf(unit *Unit) float64 {
+	if !target.SwitchTargetsOnThreat {
+		return 0
+	}
+	return target.threat[unit.UnitIndex]
+}
+
+// Adds threat of a player or pet on this target, and switches to it if it pulled aggro.
+func (target *Target) AddThreat(sim *Simulation, unit *Unit, threat float64) {
+	if !target.SwitchTargetsOnThreat || !target.IsEnabled() || unit.Type == EnemyUnit || threat == 0 || sim.CurrentTime < 0 {
+		return
+	}
+
+	target.threat[unit.UnitIndex] = max(target.threat[unit.UnitIndex]+threat, 0)
+
+	holder := target.CurrentTarget
+	if unit != holder {
+		if target.pullsAggro(unit, holder) {
+			target.setAggro(sim, unit)
+		}
+		return
+	}
+
+	// The holder dropping threat, e.g. from Feint, can hand aggro to whoever is highest.
+	if threat < 0 {
+		var highest *Unit
+		for _, other := range target.Env.Raid.AllUnits {
+			if other != holder && other.IsEnabled() && (highest == nil || target.threat[other.UnitIndex] > target.threat[highest.UnitIndex]) {
+				highest = other
+			}
+		}
+		if highest != nil && target.pullsAggro(highest, holder) {
+			target.setAggro(sim, highest)
+		}
+	}
+}
+
+// Whether unit has enough threat to pull aggro from holder. A tank which hasn't generated
+// threat yet keeps aggro, as it pulled the target.
+func (target *Target) pullsAggro(unit *Unit, holder *Unit) bool {
+	if !unit.IsEnabled() {
+		return false
+	}
+	if holder == nil {
+		return target.threat[unit.UnitIndex] > 0
+	}
+
+	holderThreat := target.threat[holder.UnitIndex]
+	if holderThreat == 0 && holder == target.tank {
+		return false
+	}
+
+	threshold := RangedAggroThreshold
+	if unit.DistanceFromTarget <= MaxMeleeAttackRange {
+		threshold = MeleeAggroThreshold
+	}
+	return target.threat[unit.UnitIndex] > holderThreat*threshold
+}
+
+func (target *Target) setAggro(sim *Simulation, unit *Unit) {
+	previous := target.CurrentTarget
+	target.endAggro(sim)
+
+	target.CurrentTarget = unit
+	target.aggroSince = sim.CurrentTime
+	// Only pulls away from the tank count, not the tank taking the target back.
+	if unit != target.tank {
+		unit.Metrics.AggroGained++
+	}
+
+	if previous == nil {
+		target.AutoAttacks.EnableAutoSwing(sim)
+	}
+
+	if sim.Log != nil {
+		target.Log(sim, "%s pulled aggro with %0.1f threat", unit.Label, target.threat[unit.UnitIndex])
+	}
+}
+
+// Credits the aggro holder with the time it held aggro, when it loses aggro, the target despawns
+// or the iteration ends.
+func (target *Target) endAggro(sim *Simulation) {
+	if !target.SwitchTargetsOnThreat || !target.IsEnabled() || target.CurrentTarget == nil {
+		return
+	}
+	target.CurrentTarget.Metrics.AggroTime += sim.CurrentTime - max(target.aggroSince, 0)
+}
+
+// Adds threat of a unit to all targets, e.g. for healing or resource gains.
+func (encounter *Encounter) addThreatToAll(sim *Simulation, unit *Unit, threat float64) {
+	for _, target := range encounter.Targets {
+		target.AddThreat(sim, unit, threat)
+	}
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/wowsims/sod/sim/core/proto"
+	"github.com/wowsims/sod/sim/core/simsignals"
+)
+
+func threatTestRequest() *proto.RaidSimRequest {
+	rsr := fakeSimRequest()
+	rsr.SimOptions.Iterations = 20
+	rsr.Encounter.Duration = 60
+	rsr.Raid.Parties[0].Players[0].Rotation = fakeDotRotation()
+	party := rsr.Raid.Parties[0]
+	tank := &proto.Player{
+		Name:      "Tank",
+		Class:     proto.Class_ClassShaman,
+		Consumes:  &proto.Consumes{},
+		Buffs:     &proto.IndividualBuffs{},
+		Spec:      &proto.Player_ElementalShaman{},
+		Equipment: &proto.EquipmentSpec{},
+	}
+	party.Players = append(party.Players, tank)
+	rsr.Raid.Tanks = []*proto.UnitReference{{Type: proto.UnitReference_Player, Index: 1}}
+
+	target := rsr.Encounter.Targets[0]
+	target.SwitchTargetsOnThreat = true
+	target.SwingSpeed = 2
+	target.MinBaseDamage = 100
+	return rsr
+}
+
+func TestThreatPullRules(t *testing.T) {
+	rsr := threatTestRequest()
+	sim := NewSim(rsr, simsignals.CreateSignals())
+	sim.Reset()
+
+	target := sim.Encounter.Targets[0]
+	caster := &sim.Raid.Parties[0].Players[0].GetCharacter().Unit
+	tank := &sim.Raid.Parties[0].Players[1].GetCharacter().Unit
+	if target.CurrentTarget != tank {
+		t.Fatalf("Expected the target to start on its tank")
+	}
+
+	// The tank keeps aggro until it generated threat, as it pulled the target.
+	target.AddThreat(sim, caster, 50)
+	if target.CurrentTarget != tank {
+		t.Fatalf("Expected the tank to keep aggro before generating threat")
+	}
+
+	target.AddThreat(sim, tank, 100)
+	caster.DistanceFromTarget = 30
+	target.AddThreat(sim, caster, 75) // 125 threat, between 110% and 130%
+	if target.CurrentTarget != tank {
+		t.Errorf("Expected a ranged player to need 130%% of the tank's threat, pulled with %0.1f", target.ThreatOf(caster))
+	}
+
+	caster.DistanceFromTarget = 5
+	target.AddThreat(sim, caster, 0.1)
+	if target.CurrentTarget != caster {
+		t.Errorf("Expected a melee player to pull aggro at 110%% of the tank's threat")
+	}
+
+	// The tank has to get back to 110% of the caster's threat, or the caster has to drop threat.
+	target.AddThreat(sim, tank, 30)
+	if target.CurrentTarget != caster {
+		t.Errorf("Expected the caster to keep aggro")
+	}
+	target.AddThreat(sim, caster, -100)
+	if target.CurrentTarget != tank {
+		t.Errorf("Expected the tank to regain aggro after the caster dropped threat")
+	}
+
+	if caster.Metrics.AggroGained != 1 || tank.Metrics.AggroGained != 0 {
+		t.Errorf("Expected 1 aggro gain for the caster and none for the tank, got %d and %d", caster.Metrics.AggroGained, tank.Metrics.AggroGained)
+	}
+}
+
+func TestThreatTankRegainsAggro(t *testing.T) {
+	rsr := threatTestRequest()
+	sim := NewSim(rsr, simsignals.CreateSignals())
+	sim.Reset()
+
+	target := sim.Encounter.Targets[0]
+	caster := &sim.Raid.Parties[0].Players[0].GetCharacter().Unit
+	tank := &sim.Raid.Parties[0].Players[1].GetCharacter().Unit
+	caster.DistanceFromTarget = 5
+
+	target.AddThreat(sim, tank, 100)
+	for i := 1; i <= 2; i++ {
+		target.AddThreat(sim, caster, 2*target.ThreatOf(tank)-target.ThreatOf(caster))
+		if target.CurrentTarget != caster {
+			t.Fatalf("Expected the caster to pull aggro")
+		}
+		target.AddThreat(sim, tank, 2*target.ThreatOf(caster)-target.ThreatOf(tank))
+		if target.CurrentTarget != tank {
+			t.Fatalf("Expected the tank to regain aggro")
+		}
+
+		if caster.Metrics.AggroGained != int32(i) || tank.Metrics.AggroGained != 0 {
+			t.Errorf("Expected %d aggro gains for the caster and none for the tank, got %d and %d", i, caster.Metrics.AggroGained, tank.Metrics.AggroGained)
+		}
+	}
+}
+
+func TestThreatWithoutTank(t *testing.T) {
+	rsr := threatTestRequest()
+	rsr.Raid.Tanks = nil
+
+	result := RunRaidSim(rsr)
+	if result.Error != nil {
+		t.Fatalf("Sim failed: %s", result.Error.Message)
+	}
+
+	caster := result.RaidMetrics.Parties[0].Players[0]
+	if caster.AggroGainedAvg != 1 {
+		t.Errorf("Expected the caster to pull aggro once, got %0.2f", caster.AggroGainedAvg)
+	}
+	if caster.SecondsWithAggroAvg <= 50 || caster.SecondsWithAggroAvg > 60 {
+		t.Errorf("Expected the caster to hold aggro for most of the fight, got %0.3fs", caster.SecondsWithAggroAvg)
+	}
+	if caster.Dtps.Avg <= 0 {
+		t.Errorf("Expected the caster to take boss swings")
+	}
+}
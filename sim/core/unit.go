@@ -1,6 +1,7 @@
 package core
 
 import (
+	"fmt"
 	"math"
 	"time"
 
@@ -158,7 +159,7 @@ type Unit struct {
 
 // Units can be disabled for several reasons:
 //  1. Downtime for temporary pets (e.g. Water Elemental)
-//  2. Enemy units in various phases (not yet implemented)
+//  2. Enemy adds which have not spawned yet or have despawned
 //  3. Dead units (not yet implemented)
 func (unit *Unit) IsEnabled() bool {
 	return unit.enabled
@@ -185,7 +186,7 @@ func (unit *Unit) LogLabel() string {
 }
 
 func (unit *Unit) Log(sim *Simulation, message string, vals ...interface{}) {
-	sim.Log(unit.LogLabel()+" "+message, vals...)
+	sim.LogEvent(unit, &proto.CombatLogEvent{Event: &proto.CombatLogEvent_Message{Message: fmt.Sprintf(message, vals...)}})
 }
 
 func (unit *Unit) GetInitialStat(stat stats.Stat) float64 {
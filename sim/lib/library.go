@@ -124,8 +124,10 @@ func getDatabase(itemIds *int32, numItems int32, enchantIds *int32, numEnchants
 	for i, enchantId := range eids {
 		enchant := core.EnchantsByEffectID[enchantId]
 		simDB.Enchants[i] = &proto.SimEnchant{
-			EffectId: enchant.EffectID,
-			Stats:    enchant.Stats[:],
+			EffectId:   enchant.EffectID,
+			Stats:      enchant.Stats[:],
+			Type:       enchant.Type,
+			ExtraTypes: enchant.ExtraTypes,
 		}
 	}
 	out, err := protojson.Marshal(simDB)
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
@@ -26,6 +27,7 @@ import (
 	proto "github.com/wowsims/sod/sim/core/proto"
 	"github.com/wowsims/sod/sim/core/simsignals"
 
+	"google.golang.org/protobuf/encoding/protojson"
 	googleProto "google.golang.org/protobuf/proto"
 )
 
@@ -94,6 +96,21 @@ var handlers = map[string]apiHandler{
 	"/raidSim": {msg: func() googleProto.Message { return &proto.RaidSimRequest{} }, handle: func(msg googleProto.Message) googleProto.Message {
 		return core.RunRaidSim(msg.(*proto.RaidSimRequest))
 	}},
+	"/compareSims": {msg: func() googleProto.Message { return &proto.CompareSimsRequest{} }, handle: func(msg googleProto.Message) googleProto.Message {
+		return core.CompareSims(msg.(*proto.CompareSimsRequest))
+	}},
+	"/formatAplRotation": {msg: func() googleProto.Message { return &proto.FormatAPLRotationRequest{} }, handle: func(msg googleProto.Message) googleProto.Message {
+		return core.FormatAPLRotationText(msg.(*proto.FormatAPLRotationRequest))
+	}},
+	"/parseAplRotation": {msg: func() googleProto.Message { return &proto.ParseAPLRotationRequest{} }, handle: func(msg googleProto.Message) googleProto.Message {
+		return core.ParseAPLRotationText(msg.(*proto.ParseAPLRotationRequest))
+	}},
+	"/lintAplRotation": {msg: func() googleProto.Message { return &proto.LintAPLRotationRequest{} }, handle: func(msg googleProto.Message) googleProto.Message {
+		return core.LintAPLRotation(msg.(*proto.LintAPLRotationRequest))
+	}},
+	"/replayIteration": {msg: func() googleProto.Message { return &proto.ReplayIterationRequest{} }, handle: func(msg googleProto.Message) googleProto.Message {
+		return core.ReplayIteration(msg.(*proto.ReplayIterationRequest))
+	}},
 	"/statWeights": {msg: func() googleProto.Message { return &proto.StatWeightsRequest{} }, handle: func(msg googleProto.Message) googleProto.Message {
 		return core.StatWeights(msg.(*proto.StatWeightsRequest))
 	}},
@@ -139,15 +156,126 @@ type asyncAPIHandler struct {
 	handle func(googleProto.Message, chan *proto.ProgressMetrics, string)
 }
 
+// Number of updates an asyncProgress queues for its streaming clients.
+const maxQueuedUpdates = 100
+
 type asyncProgress struct {
 	id             string
 	latestProgress atomic.Value
+
+	// Updates which not every streaming client has received yet, in order. first is
+	// the sequence number of updates[0], and clients maps each streaming client to the
+	// sequence number of the next update it receives. Once maxQueuedUpdates are queued,
+	// push waits for the clients to catch up, or drops the oldest update if there are
+	// none. changed is closed and replaced on each update and each catch up.
+	mut     sync.Mutex
+	updates []*proto.ProgressMetrics
+	first   int
+	clients map[int]int
+	nextID  int
+	changed chan struct{}
+	closed  bool
+}
+
+func (p *asyncProgress) push(progress *proto.ProgressMetrics) {
+	p.latestProgress.Store(progress)
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	for len(p.updates) >= maxQueuedUpdates {
+		if len(p.clients) == 0 {
+			p.updates = p.updates[1:]
+			p.first++
+			break
+		}
+		changed := p.changed
+		p.mut.Unlock()
+		<-changed
+		p.mut.Lock()
+	}
+	p.updates = append(p.updates, progress)
+	p.notify()
+}
+
+// notify wakes everyone waiting for changed. Must be called with mut held.
+func (p *asyncProgress) notify() {
+	if !p.closed {
+		close(p.changed)
+		p.changed = make(chan struct{})
+	}
+}
+
+// finish marks that no more updates will be pushed.
+func (p *asyncProgress) finish() {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	if !p.closed {
+		p.closed = true
+		close(p.changed)
+	}
+}
+
+// subscribe registers a streaming client, which receives all queued updates and every
+// update after them. Clients must be unsubscribed once they stop reading.
+func (p *asyncProgress) subscribe() int {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	id := p.nextID
+	p.nextID++
+	p.clients[id] = p.first
+	return id
+}
+
+func (p *asyncProgress) unsubscribe(client int) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	delete(p.clients, client)
+	p.dropReceived()
+	p.notify()
+}
+
+// receive returns the updates the client hasn't received yet, a channel which is closed
+// once there is something new, and whether more updates can still arrive.
+func (p *asyncProgress) receive(client int) ([]*proto.ProgressMetrics, <-chan struct{}, bool) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	updates := p.updates[p.clients[client]-p.first:]
+	p.clients[client] = p.first + len(p.updates)
+	if p.dropReceived() {
+		p.notify()
+	}
+	return updates, p.changed, !p.closed
+}
+
+// dropReceived drops the updates every client has received, and returns whether there
+// were any. Without clients, updates are kept for clients which connect later, up to
+// maxQueuedUpdates. Must be called with mut held.
+func (p *asyncProgress) dropReceived() bool {
+	if len(p.clients) == 0 {
+		return false
+	}
+	received := p.first + len(p.updates)
+	for _, next := range p.clients {
+		received = min(received, next)
+	}
+	if received == p.first {
+		return false
+	}
+	p.updates = p.updates[received-p.first:]
+	p.first = received
+	return true
+}
+
+func isFinalProgress(progress *proto.ProgressMetrics) bool {
+	return progress.FinalRaidResult != nil || progress.FinalWeightResult != nil || progress.FinalBulkResult != nil
 }
 
 func (s *server) addNewSim() *asyncProgress {
 	newID := uuid.NewString()
 	simProgress := &asyncProgress{
-		id: newID,
+		id:      newID,
+		clients: map[int]int{},
+		changed: make(chan struct{}),
 	}
 	simProgress.latestProgress.Store(&proto.ProgressMetrics{})
 
@@ -172,7 +300,7 @@ func (s *server) handleAsyncAPI(w http.ResponseWriter, r *http.Request) {
 	}
 
 	msg := handler.msg()
-	if err := googleProto.Unmarshal(body, msg); err != nil {
+	if err := unmarshalRequest(r, body, msg); err != nil {
 		log.Printf("Failed to parse request: %s", err.Error())
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -190,6 +318,7 @@ func (s *server) handleAsyncAPI(w http.ResponseWriter, r *http.Request) {
 	// Now launch a background process that pulls progress reports off the reporter channel
 	// and pushes it into the async progress cache.
 	go func() {
+		defer simProgress.finish()
 		for {
 			select {
 			case <-time.After(time.Minute * 10):
@@ -202,8 +331,8 @@ func (s *server) handleAsyncAPI(w http.ResponseWriter, r *http.Request) {
 				if progMetric == nil {
 					return
 				}
-				simProgress.latestProgress.Store(progMetric)
-				if progMetric.FinalRaidResult != nil || progMetric.FinalWeightResult != nil || progMetric.FinalBulkResult != nil {
+				simProgress.push(progMetric)
+				if isFinalProgress(progMetric) {
 					return
 				}
 			}
@@ -214,15 +343,7 @@ func (s *server) handleAsyncAPI(w http.ResponseWriter, r *http.Request) {
 		ProgressId: simProgress.id,
 	}
 
-	outbytes, err := googleProto.Marshal(protoResult)
-	if err != nil {
-		log.Printf("[ERROR] Failed to marshal result: %s", err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Add("Content-Type", "application/x-protobuf")
-	w.Write(outbytes)
+	writeResult(w, r, protoResult)
 }
 
 func (s *server) setupAsyncServer() {
@@ -238,7 +359,7 @@ func (s *server) setupAsyncServer() {
 			return
 		}
 		msg := &proto.AsyncAPIResult{}
-		if err := googleProto.Unmarshal(body, msg); err != nil {
+		if err := unmarshalRequest(r, body, msg); err != nil {
 			log.Printf("Failed to parse request: %s", err.Error())
 			w.WriteHeader(http.StatusInternalServerError)
 			return
@@ -253,22 +374,86 @@ func (s *server) setupAsyncServer() {
 			return
 		}
 		latest := progress.latestProgress.Load().(*proto.ProgressMetrics)
-		outbytes, err := googleProto.Marshal(latest)
-		if err != nil {
-			log.Printf("[ERROR] Failed to marshal result: %s", err.Error())
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
 
 		// If this was the last result, delete the cache for this simulation.
-		if latest.FinalRaidResult != nil || latest.FinalWeightResult != nil || latest.FinalBulkResult != nil {
+		if isFinalProgress(latest) {
 			s.progMut.Lock()
 			delete(s.asyncProgresses, msg.ProgressId)
 			s.progMut.Unlock()
 		}
-		w.Header().Add("Content-Type", "application/x-protobuf")
-		w.Write(outbytes)
+		writeResult(w, r, latest)
 	})))
+
+	// asyncProgressStream pushes every progress update of a simulation as server-sent events.
+	http.Handle("/asyncProgressStream", corsMiddleware(http.HandlerFunc(s.handleProgressStream)))
+}
+
+// handleProgressStream streams the progress of the simulation given by the progressId query
+// parameter. Each update is sent as a protojson encoded ProgressMetrics in a "progress" event,
+// except the last which is sent as a "final" event. If the simulation stops reporting without a
+// final result, a "closed" event is sent instead. The stream ends after either of those.
+func (s *server) handleProgressStream(w http.ResponseWriter, r *http.Request) {
+	progressId := r.URL.Query().Get("progressId")
+	s.progMut.RLock()
+	progress, ok := s.asyncProgresses[progressId]
+	s.progMut.RUnlock()
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Printf("[ERROR] Streaming is not supported by the response writer")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	client := progress.subscribe()
+	defer progress.unsubscribe(client)
+	for {
+		updates, changed, open := progress.receive(client)
+		for _, update := range updates {
+			data, err := protojson.Marshal(update)
+			if err != nil {
+				log.Printf("[ERROR] Failed to marshal result: %s", err.Error())
+				return
+			}
+
+			event := "progress"
+			if isFinalProgress(update) {
+				event = "final"
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+
+			if event == "final" {
+				flusher.Flush()
+				s.progMut.Lock()
+				delete(s.asyncProgresses, progressId)
+				s.progMut.Unlock()
+				return
+			}
+		}
+
+		if !open {
+			fmt.Fprint(w, "event: closed\ndata: {}\n\n")
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
 }
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -422,7 +607,7 @@ func handleAPI(w http.ResponseWriter, r *http.Request) {
 	}
 
 	msg := handler.msg()
-	if err := googleProto.Unmarshal(body, msg); err != nil {
+	if err := unmarshalRequest(r, body, msg); err != nil {
 		log.Printf("Failed to parse request: %s", err.Error())
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -436,13 +621,53 @@ func handleAPI(w http.ResponseWriter, r *http.Request) {
 
 	result := handler.handle(msg)
 
-	outbytes, err := googleProto.Marshal(result)
+	writeResult(w, r, result)
+}
+
+// unmarshalRequest decodes body into msg as protojson if the request says it is JSON, and as
+// binary protobuf otherwise.
+func unmarshalRequest(r *http.Request, body []byte, msg googleProto.Message) error {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mediaType == "application/json" {
+		return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, msg)
+	}
+	return googleProto.Unmarshal(body, msg)
+}
+
+// writeResult encodes result as protojson if the Accept header asks for JSON, and as binary
+// protobuf otherwise.
+func writeResult(w http.ResponseWriter, r *http.Request, result googleProto.Message) {
+	contentType := "application/x-protobuf"
+	marshal := googleProto.Marshal
+	if acceptsJSON(r.Header.Get("Accept")) {
+		contentType = "application/json"
+		marshal = protojson.Marshal
+	}
+
+	outbytes, err := marshal(result)
 	if err != nil {
 		log.Printf("[ERROR] Failed to marshal result: %s", err.Error())
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Add("Content-Type", "application/x-protobuf")
+	w.Header().Add("Content-Type", contentType)
 	w.Write(outbytes)
 }
+
+// acceptsJSON reports whether the Accept header lists application/json ahead of
+// application/x-protobuf. Quality values aren't considered.
+func acceptsJSON(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/json":
+			return true
+		case "application/x-protobuf":
+			return false
+		}
+	}
+	return false
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -13,6 +14,7 @@ import (
 	_ "github.com/wowsims/sod/sim/common"
 	"github.com/wowsims/sod/sim/core"
 	"github.com/wowsims/sod/sim/core/proto"
+	"google.golang.org/protobuf/encoding/protojson"
 	googleProto "google.golang.org/protobuf/proto"
 )
 
@@ -108,3 +110,177 @@ func TestIndividualSim(t *testing.T) {
 
 	log.Printf("RESULT: %#v", rsr)
 }
+
+func TestAsyncProgressStream(t *testing.T) {
+	req := &proto.RaidSimRequest{
+		Raid: core.SinglePlayerRaidProto(
+			&proto.Player{
+				Race:      proto.Race_RaceTroll,
+				Class:     proto.Class_ClassShaman,
+				Equipment: p1Equip,
+				Spec:      basicSpec,
+			},
+			&proto.PartyBuffs{},
+			&proto.RaidBuffs{},
+			&proto.Debuffs{}),
+		Encounter: &proto.Encounter{
+			Duration: 120,
+			Targets: []*proto.Target{
+				{},
+			},
+		},
+		SimOptions: &proto.SimOptions{
+			Iterations: 100,
+			RandomSeed: 1,
+		},
+	}
+
+	msgBytes, err := googleProto.Marshal(req)
+	if err != nil {
+		t.Fatalf("Failed to encode request: %s", err.Error())
+	}
+
+	r, err := http.Post("http://localhost:3339/raidSimAsync", "application/x-protobuf", bytes.NewReader(msgBytes))
+	if err != nil {
+		t.Fatalf("Failed to POST request: %s", err.Error())
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Fatalf("Failed to read result body: %s", err.Error())
+	}
+	asyncResult := &proto.AsyncAPIResult{}
+	if err := googleProto.Unmarshal(body, asyncResult); err != nil {
+		t.Fatalf("Failed to parse async result: %s", err.Error())
+	}
+
+	stream, err := http.Get("http://localhost:3339/asyncProgressStream?progressId=" + asyncResult.ProgressId)
+	if err != nil {
+		t.Fatalf("Failed to GET stream: %s", err.Error())
+	}
+	defer stream.Body.Close()
+	if contentType := stream.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got content type %q", contentType)
+	}
+
+	var event string
+	var final *proto.ProgressMetrics
+	scanner := bufio.NewScanner(stream.Body)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && event == "final":
+			final = &proto.ProgressMetrics{}
+			if err := protojson.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), final); err != nil {
+				t.Fatalf("Failed to parse final event: %s", err.Error())
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Failed to read stream: %s", err.Error())
+	}
+
+	if final == nil || final.FinalRaidResult == nil {
+		t.Fatalf("Stream ended without a final raid result, last event: %q", event)
+	}
+}
+
+func TestIndividualSimJSON(t *testing.T) {
+	req := &proto.RaidSimRequest{
+		Raid: core.SinglePlayerRaidProto(
+			&proto.Player{
+				Race:      proto.Race_RaceTroll,
+				Class:     proto.Class_ClassShaman,
+				Equipment: p1Equip,
+				Spec:      basicSpec,
+			},
+			&proto.PartyBuffs{},
+			&proto.RaidBuffs{},
+			&proto.Debuffs{}),
+		Encounter: &proto.Encounter{
+			Duration: 120,
+			Targets: []*proto.Target{
+				{},
+			},
+		},
+		SimOptions: &proto.SimOptions{
+			Iterations: 100,
+			RandomSeed: 1,
+		},
+	}
+
+	msgBytes, err := protojson.Marshal(req)
+	if err != nil {
+		t.Fatalf("Failed to encode request: %s", err.Error())
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, "http://localhost:3339/raidSim", bytes.NewReader(msgBytes))
+	if err != nil {
+		t.Fatalf("Failed to create request: %s", err.Error())
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	r, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		t.Fatalf("Failed to POST request: %s", err.Error())
+	}
+	if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+		t.Fatalf("Expected a JSON response, got content type %q", contentType)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Fatalf("Failed to read result body: %s", err.Error())
+	}
+
+	rsr := &proto.RaidSimResult{}
+	if err := protojson.Unmarshal(body, rsr); err != nil {
+		t.Fatalf("Failed to parse result: %s", err.Error())
+	}
+}
+
+func TestAsyncProgressDeliversEveryUpdate(t *testing.T) {
+	progress := &asyncProgress{clients: map[int]int{}, changed: make(chan struct{})}
+	// Updates pushed before the client connects are still delivered.
+	progress.push(&proto.ProgressMetrics{CompletedIterations: 0})
+	client := progress.subscribe()
+
+	// More updates than are queued, so pushing has to wait for the client.
+	numUpdates := int32(3 * maxQueuedUpdates)
+	go func() {
+		defer progress.finish()
+		for i := int32(1); i < numUpdates; i++ {
+			progress.push(&proto.ProgressMetrics{CompletedIterations: i})
+		}
+		progress.push(&proto.ProgressMetrics{CompletedIterations: numUpdates, FinalRaidResult: &proto.RaidSimResult{}})
+	}()
+
+	var received []*proto.ProgressMetrics
+	for {
+		updates, changed, open := progress.receive(client)
+		received = append(received, updates...)
+		if !open {
+			break
+		}
+		<-changed
+	}
+	progress.unsubscribe(client)
+
+	if len(received) != int(numUpdates)+1 {
+		t.Fatalf("Expected %d updates, got %d", numUpdates+1, len(received))
+	}
+	for i, update := range received {
+		if update.CompletedIterations != int32(i) {
+			t.Fatalf("Expected update %d to have %d completed iterations, got %d", i, i, update.CompletedIterations)
+		}
+	}
+	if !isFinalProgress(received[len(received)-1]) {
+		t.Errorf("Expected the last update to be final")
+	}
+	if len(progress.updates) > maxQueuedUpdates {
+		t.Errorf("Expected at most %d queued updates, got %d", maxQueuedUpdates, len(progress.updates))
+	}
+}
@@ -229,6 +229,11 @@ class TargetPicker extends Input<Encounter, TargetProto> {
 	private readonly levelPicker: Input<null, number>;
 	private readonly mobTypePicker: Input<null, number>;
 	private readonly tankIndexPicker: Input<null, number>;
+	private readonly switchTargetsOnThreatPicker: Input<null, boolean>;
+	private readonly spawnTimePicker: Input<null, number>;
+	private readonly despawnAfterPicker: Input<null, number>;
+	private readonly diesAtHealthPicker: Input<null, boolean>;
+	private readonly respawnIntervalPicker: Input<null, number>;
 	private readonly statPickers: Array<Input<null, number>>;
 	private readonly swingSpeedPicker: Input<null, number>;
 	private readonly minBaseDamagePicker: Input<null, number>;
@@ -370,6 +375,71 @@ class TargetPicker extends Input<Encounter, TargetProto> {
 				encounter.targetsChangeEmitter.emit(eventID);
 			},
 		});
+		this.switchTargetsOnThreatPicker = new BooleanPicker(section1, null, {
+			id: 'target-picker-switch-targets-on-threat',
+			extraCssClasses: ['threat-metrics'],
+			label: 'Switch Targets On Threat',
+			labelTooltip:
+				'If checked, this enemy attacks whoever has the most threat. Players pull aggro at 110% of the threat of the current target in melee range, and 130% at range.',
+			inline: true,
+			reverse: true,
+			changedEvent: () => encounter.targetsChangeEmitter,
+			getValue: () => this.getTarget().switchTargetsOnThreat,
+			setValue: (eventID: EventID, _: null, newValue: boolean) => {
+				this.getTarget().switchTargetsOnThreat = newValue;
+				encounter.targetsChangeEmitter.emit(eventID);
+			},
+		});
+
+		this.spawnTimePicker = new NumberPicker(section1, null, {
+			id: 'target-picker-spawn-time',
+			label: 'Spawn Time',
+			labelTooltip: 'Seconds into the fight at which this enemy spawns, for adds. Set to 0 to be present from the start.',
+			float: true,
+			changedEvent: () => encounter.targetsChangeEmitter,
+			getValue: () => this.getTarget().spawnTime,
+			setValue: (eventID: EventID, _: null, newValue: number) => {
+				this.getTarget().spawnTime = newValue;
+				encounter.targetsChangeEmitter.emit(eventID);
+			},
+		});
+		this.despawnAfterPicker = new NumberPicker(section1, null, {
+			id: 'target-picker-despawn-after',
+			label: 'Despawn After',
+			labelTooltip: 'Seconds after each spawn at which this enemy despawns. Set to 0 to stay until the end of the fight.',
+			float: true,
+			changedEvent: () => encounter.targetsChangeEmitter,
+			getValue: () => this.getTarget().despawnAfter,
+			setValue: (eventID: EventID, _: null, newValue: number) => {
+				this.getTarget().despawnAfter = newValue;
+				encounter.targetsChangeEmitter.emit(eventID);
+			},
+		});
+		this.respawnIntervalPicker = new NumberPicker(section1, null, {
+			id: 'target-picker-respawn-interval',
+			label: 'Respawn Interval',
+			labelTooltip: 'If set, this enemy spawns again every this many seconds, unless it is still alive.',
+			float: true,
+			changedEvent: () => encounter.targetsChangeEmitter,
+			getValue: () => this.getTarget().respawnInterval,
+			setValue: (eventID: EventID, _: null, newValue: number) => {
+				this.getTarget().respawnInterval = newValue;
+				encounter.targetsChangeEmitter.emit(eventID);
+			},
+		});
+		this.diesAtHealthPicker = new BooleanPicker(section1, null, {
+			id: 'target-picker-dies-at-health',
+			label: 'Dies At Health',
+			labelTooltip: 'Whether this enemy dies and despawns once it has taken its health in damage.',
+			inline: true,
+			reverse: true,
+			changedEvent: () => encounter.targetsChangeEmitter,
+			getValue: () => this.getTarget().diesAtHealth,
+			setValue: (eventID: EventID, _: null, newValue: boolean) => {
+				this.getTarget().diesAtHealth = newValue;
+				encounter.targetsChangeEmitter.emit(eventID);
+			},
+		});
 
 		this.targetInputPickers = makeTargetInputsPicker(section1, encounter, this.targetIndex);
 
@@ -499,6 +569,11 @@ class TargetPicker extends Input<Encounter, TargetProto> {
 			level: this.levelPicker.getInputValue(),
 			mobType: this.mobTypePicker.getInputValue(),
 			tankIndex: this.tankIndexPicker.getInputValue(),
+			switchTargetsOnThreat: this.switchTargetsOnThreatPicker.getInputValue(),
+			spawnTime: this.spawnTimePicker.getInputValue(),
+			despawnAfter: this.despawnAfterPicker.getInputValue(),
+			diesAtHealth: this.diesAtHealthPicker.getInputValue(),
+			respawnInterval: this.respawnIntervalPicker.getInputValue(),
 			swingSpeed: this.swingSpeedPicker.getInputValue(),
 			minBaseDamage: this.minBaseDamagePicker.getInputValue(),
 			dualWield: this.dualWieldPicker.getInputValue(),
@@ -522,6 +597,11 @@ class TargetPicker extends Input<Encounter, TargetProto> {
 		this.levelPicker.setInputValue(newValue.level);
 		this.mobTypePicker.setInputValue(newValue.mobType);
 		this.tankIndexPicker.setInputValue(newValue.tankIndex);
+		this.switchTargetsOnThreatPicker.setInputValue(newValue.switchTargetsOnThreat);
+		this.spawnTimePicker.setInputValue(newValue.spawnTime);
+		this.despawnAfterPicker.setInputValue(newValue.despawnAfter);
+		this.diesAtHealthPicker.setInputValue(newValue.diesAtHealth);
+		this.respawnIntervalPicker.setInputValue(newValue.respawnInterval);
 		this.swingSpeedPicker.setInputValue(newValue.swingSpeed);
 		this.minBaseDamagePicker.setInputValue(newValue.minBaseDamage);
 		this.dualWieldPicker.setInputValue(newValue.dualWield);
@@ -10,6 +10,7 @@ import {
 	APLActionCastPaladinPrimarySeal,
 	APLActionCastSpell,
 	APLActionCatOptimalRotationAction,
+	APLActionCallList,
 	APLActionChangeTarget,
 	APLActionChannelSpell,
 	APLActionCustomRotation,
@@ -21,8 +22,10 @@ import {
 	APLActionPaladinCastWithMacro,
 	APLActionPaladinCastWithMacro_Macro as PaladinMacro,
 	APLActionResetSequence,
+	APLActionRunList,
 	APLActionSchedule,
 	APLActionSequence,
+	APLActionSetVariable,
 	APLActionStrictSequence,
 	APLActionTriggerICD,
 	APLActionWait,
@@ -553,6 +556,25 @@ const actionKindFactories: { [f in NonNullable<APLActionKind>]: ActionKindConfig
 		newValue: APLActionStrictSequence.create,
 		fields: [actionListFieldConfig('actions')],
 	}),
+	['callList']: inputBuilder({
+		label: 'Call List',
+		submenu: ['Action Lists'],
+		shortDescription: 'Performs the first ready action from the named action list, or continues with the next action if none are ready.',
+		includeIf: (player: Player<any>, isPrepull: boolean) => !isPrepull,
+		newValue: () => APLActionCallList.create(),
+		fields: [AplHelpers.stringFieldConfig('listName')],
+	}),
+	['runList']: inputBuilder({
+		label: 'Run List',
+		submenu: ['Action Lists'],
+		shortDescription: 'Performs the first ready action from the named action list.',
+		fullDescription: `
+			<p>Unlike <b>Call List</b>, actions after this one are never evaluated, even if no action in the list is ready.</p>
+		`,
+		includeIf: (player: Player<any>, isPrepull: boolean) => !isPrepull,
+		newValue: () => APLActionRunList.create(),
+		fields: [AplHelpers.stringFieldConfig('listName')],
+	}),
 	['changeTarget']: inputBuilder({
 		label: 'Change Target',
 		submenu: ['Misc'],
@@ -607,6 +629,22 @@ const actionKindFactories: { [f in NonNullable<APLActionKind>]: ActionKindConfig
 		newValue: () => APLActionCancelAura.create(),
 		fields: [AplHelpers.actionIdFieldConfig('auraId', 'auras')],
 	}),
+	['setVariable']: inputBuilder({
+		label: 'Set Variable',
+		submenu: ['Misc'],
+		shortDescription: 'Stores a value in a named variable, which can be read back with the <b>Variable</b> value.',
+		fullDescription: `
+			<p>Variables are reset to 0 at the start of each iteration. In the priority list and action lists, the variable is set as soon as this action is reached, and the evaluation continues with the next action.</p>
+		`,
+		newValue: () => APLActionSetVariable.create(),
+		fields: [
+			AplHelpers.stringFieldConfig('name'),
+			AplHelpers.booleanFieldConfig('isDuration', 'Duration', {
+				labelTooltip: 'If checked, the variable holds a duration instead of a number.',
+			}),
+			AplValues.valueFieldConfig('value'),
+		],
+	}),
 	['triggerIcd']: inputBuilder({
 		label: 'Trigger ICD',
 		submenu: ['Misc'],
@@ -27,6 +27,7 @@ import {
 	APLValueCurrentHealthPercent,
 	APLValueCurrentMana,
 	APLValueCurrentManaPercent,
+	APLValueCurrentPhase,
 	APLValueCurrentRage,
 	APLValueCurrentSealRemainingTime,
 	APLValueCurrentTime,
@@ -68,7 +69,9 @@ import {
 	APLValueSpellTravelTime,
 	APLValueTargetMobType,
 	APLValueTimeToEnergyTick,
+	APLValueTimeToNextPhase,
 	APLValueTotemRemainingTime,
+	APLValueVariable,
 	APLValueWarlockCurrentPetMana,
 	APLValueWarlockCurrentPetManaPercent,
 	APLValueWarlockPetIsActive,
@@ -613,6 +616,20 @@ const valueKindFactories: { [f in NonNullable<APLValueKind>]: ValueKindConfig<AP
 		newValue: APLValueIsExecutePhase.create,
 		fields: [executePhaseThresholdFieldConfig('threshold')],
 	}),
+	currentPhase: inputBuilder({
+		label: 'Current Phase',
+		submenu: ['Encounter'],
+		shortDescription: 'Name of the encounter phase which started last, or empty before the first one.',
+		newValue: APLValueCurrentPhase.create,
+		fields: [],
+	}),
+	timeToNextPhase: inputBuilder({
+		label: 'Time to Next Phase',
+		submenu: ['Encounter'],
+		shortDescription: 'Estimated time until the next encounter phase starts, or the remaining fight duration after the last one.',
+		newValue: APLValueTimeToNextPhase.create,
+		fields: [],
+	}),
 	numberTargets: inputBuilder({
 		label: 'Number of Targets',
 		submenu: ['Encounter'],
@@ -968,6 +985,15 @@ const valueKindFactories: { [f in NonNullable<APLValueKind>]: ValueKindConfig<AP
 		fields: [AplHelpers.stringFieldConfig('sequenceName')],
 	}),
 
+	// Variable values
+	variable: inputBuilder({
+		label: 'Variable',
+		submenu: ['Variable'],
+		shortDescription: 'Value of a variable stored with the <b>Set Variable</b> action, or 0 before it is set.',
+		newValue: APLValueVariable.create,
+		fields: [AplHelpers.stringFieldConfig('name')],
+	}),
+
 	// Class/spec specific values
 	totemRemainingTime: inputBuilder({
 		label: 'Totem Remaining Time',